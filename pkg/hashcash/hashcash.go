@@ -0,0 +1,106 @@
+package hashcash
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+const (
+	nonceLength = 16
+	// MaxSolutionLength limits the size of the solution a client can send.
+	MaxSolutionLength = 32
+)
+
+// Hashcash issues proof-of-work challenges. A client solves the challenge by finding a solution,
+// such that SHA-256 of nonce concatenated with the solution starts with the required number of zero bits.
+// Every nonce can be verified only once.
+type Hashcash struct {
+	difficulty int
+	ttl        time.Duration
+
+	mu        sync.Mutex
+	nonces    map[string]time.Time
+	lastSweep time.Time
+}
+
+// Generate returns a new nonce.
+func (h *Hashcash) Generate() (string, error) {
+	b := make([]byte, nonceLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	nonce := hex.EncodeToString(b)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sweep()
+	h.nonces[nonce] = time.Now().Add(h.ttl)
+	return nonce, nil
+}
+
+// Difficulty returns number of leading zero bits a solution must produce.
+func (h *Hashcash) Difficulty() int {
+	return h.difficulty
+}
+
+// Valid returns true if the nonce was issued and has not been used yet.
+func (h *Hashcash) Valid(nonce string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	expires, ok := h.nonces[nonce]
+	return ok && time.Now().Before(expires)
+}
+
+// Verify checks the solution. The nonce is invalidated regardless of the result.
+func (h *Hashcash) Verify(nonce, solution string) bool {
+	if solution == "" || len(solution) > MaxSolutionLength {
+		return false
+	}
+
+	h.mu.Lock()
+	expires, ok := h.nonces[nonce]
+	delete(h.nonces, nonce)
+	h.mu.Unlock()
+
+	if !ok || time.Now().After(expires) {
+		return false
+	}
+	return leadingZeroBits(sha256.Sum256([]byte(nonce+solution))) >= h.difficulty
+}
+
+// sweep removes expired nonces, at most once per ttl.
+func (h *Hashcash) sweep() {
+	now := time.Now()
+	if now.Sub(h.lastSweep) < h.ttl {
+		return
+	}
+	for nonce, expires := range h.nonces {
+		if now.After(expires) {
+			delete(h.nonces, nonce)
+		}
+	}
+	h.lastSweep = now
+}
+
+func leadingZeroBits(sum [sha256.Size]byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
+
+func NewHashcash(difficulty int, ttl time.Duration) *Hashcash {
+	return &Hashcash{
+		difficulty: difficulty,
+		ttl:        ttl,
+		nonces:     make(map[string]time.Time),
+	}
+}
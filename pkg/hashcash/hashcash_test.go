@@ -0,0 +1,94 @@
+package hashcash_test
+
+import (
+	"crypto/sha256"
+	"github.com/onionltd/mono/pkg/hashcash"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const difficulty = 8
+
+// solve finds a solution the way a client does, by trying counters until the hash has enough leading zeros.
+func solve(nonce string) string {
+	for i := 0; ; i++ {
+		solution := strconv.Itoa(i)
+		sum := sha256.Sum256([]byte(nonce + solution))
+		if sum[0] == 0 {
+			return solution
+		}
+	}
+}
+
+// wrongSolution finds a solution that doesn't meet the difficulty.
+func wrongSolution(nonce string) string {
+	for i := 0; ; i++ {
+		solution := strconv.Itoa(i)
+		sum := sha256.Sum256([]byte(nonce + solution))
+		if sum[0] != 0 {
+			return solution
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	h := hashcash.NewHashcash(difficulty, time.Minute)
+	assert.Equal(t, difficulty, h.Difficulty())
+
+	nonce, err := h.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, h.Valid(nonce))
+
+	solution := solve(nonce)
+	assert.True(t, h.Verify(nonce, solution))
+	// Nonce can be verified only once.
+	assert.False(t, h.Valid(nonce))
+	assert.False(t, h.Verify(nonce, solution))
+
+	nonce, err = h.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, h.Verify(nonce, wrongSolution(nonce)))
+	// Wrong solution invalidates the nonce too.
+	assert.False(t, h.Verify(nonce, solve(nonce)))
+
+	nonce, err = h.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, h.Verify(nonce, ""))
+	assert.False(t, h.Verify("made-up", solve("made-up")))
+}
+
+func TestVerifyTooLong(t *testing.T) {
+	h := hashcash.NewHashcash(0, time.Minute)
+
+	nonce, err := h.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tooLong := make([]byte, hashcash.MaxSolutionLength+1)
+	for i := range tooLong {
+		tooLong[i] = 'a'
+	}
+	assert.False(t, h.Verify(nonce, string(tooLong)))
+}
+
+func TestVerifyExpired(t *testing.T) {
+	h := hashcash.NewHashcash(difficulty, 50*time.Millisecond)
+
+	nonce, err := h.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	solution := solve(nonce)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.False(t, h.Valid(nonce))
+	assert.False(t, h.Verify(nonce, solution))
+}
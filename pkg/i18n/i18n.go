@@ -0,0 +1,117 @@
+// Package i18n translates messages using per-locale catalogs.
+//
+// Catalogs are JSON files named after a BCP 47 language tag, e.g. `de.json`, which map source
+// messages to their translations. Source messages are written in the fallback language, so it
+// doesn't need a catalog. Whitespace in messages is not significant, runs of whitespace are
+// collapsed into a single space.
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"golang.org/x/text/language"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// Catalog maps source messages to their translations.
+type Catalog map[string]string
+
+// Bundle holds catalogs of all supported languages.
+type Bundle struct {
+	fallback language.Tag
+	tags     []language.Tag
+	catalogs map[language.Tag]Catalog
+	matcher  language.Matcher
+}
+
+// Languages returns supported languages, the fallback language first.
+func (b *Bundle) Languages() []language.Tag {
+	return b.tags
+}
+
+func (b *Bundle) Fallback() language.Tag {
+	return b.fallback
+}
+
+// Match returns the supported language that best matches the preferences. Each preference
+// is either a language tag or a value of Accept-Language header, preferences are ordered
+// from the most important. Invalid preferences are skipped.
+func (b *Bundle) Match(prefs ...string) language.Tag {
+	for _, pref := range prefs {
+		tags, _, err := language.ParseAcceptLanguage(pref)
+		if err != nil || len(tags) == 0 {
+			continue
+		}
+		_, idx, confidence := b.matcher.Match(tags...)
+		if confidence != language.No {
+			return b.tags[idx]
+		}
+	}
+	return b.fallback
+}
+
+// Translate returns the translation of the message. If there's none, the message is returned
+// as it is. The message is used as a format string if any arguments are given.
+func (b *Bundle) Translate(tag language.Tag, msg string, args ...interface{}) string {
+	msg = normalize(msg)
+	if translation, ok := b.catalogs[tag][msg]; ok {
+		msg = translation
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Add adds the catalog of the language, replacing the previous one.
+func (b *Bundle) Add(tag language.Tag, catalog Catalog) {
+	normalized := make(Catalog, len(catalog))
+	for msg, translation := range catalog {
+		normalized[normalize(msg)] = normalize(translation)
+	}
+	if _, ok := b.catalogs[tag]; !ok {
+		b.tags = append(b.tags, tag)
+	}
+	b.catalogs[tag] = normalized
+	b.matcher = language.NewMatcher(b.tags)
+}
+
+// LoadDir adds all catalogs found in dir.
+func (b *Bundle) LoadDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		tag, err := language.Parse(strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		catalog := Catalog{}
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		b.Add(tag, catalog)
+	}
+	return nil
+}
+
+func normalize(msg string) string {
+	return strings.Join(strings.Fields(msg), " ")
+}
+
+// NewBundle returns a bundle supporting only the fallback language.
+func NewBundle(fallback language.Tag) *Bundle {
+	b := &Bundle{
+		fallback: fallback,
+		catalogs: map[language.Tag]Catalog{},
+	}
+	b.Add(fallback, Catalog{})
+	return b
+}
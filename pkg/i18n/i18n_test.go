@@ -0,0 +1,42 @@
+package i18n_test
+
+import (
+	"github.com/onionltd/mono/pkg/i18n"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "i18n-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	catalog := `{"Done": "Fertig", "Used %d   times.": "%d-mal\n  verwendet."}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "de.json"), []byte(catalog), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	b := i18n.NewBundle(language.English)
+	if err := b.LoadDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []language.Tag{language.English, language.German}, b.Languages())
+
+	assert.Equal(t, language.German, b.Match("de-AT,de;q=0.9,en;q=0.5"))
+	assert.Equal(t, language.English, b.Match("fr-FR,en;q=0.5"))
+	assert.Equal(t, language.English, b.Match("fr"))
+	assert.Equal(t, language.English, b.Match("", "!!"))
+	// Explicit preferences win over the header.
+	assert.Equal(t, language.German, b.Match("de", "en"))
+
+	assert.Equal(t, "Fertig", b.Translate(language.German, "Done"))
+	assert.Equal(t, "Done", b.Translate(language.English, "Done"))
+	assert.Equal(t, "3-mal verwendet.", b.Translate(language.German, "Used %d\n times.", 3))
+	assert.Equal(t, "Missing", b.Translate(language.German, "Missing"))
+}
@@ -0,0 +1,96 @@
+package badger
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v2/pb"
+	"github.com/golang/protobuf/proto"
+	"io"
+	"time"
+)
+
+// LoadMaxPendingWrites is passed to badger's DB.Load.
+const LoadMaxPendingWrites = 256
+
+// MaxBackupChunkSize limits the size of a single length-prefixed chunk of a backup. Badger writes
+// the backup in batches of a few megabytes, a larger chunk means the stream is corrupted.
+const MaxBackupChunkSize = 64 << 20
+
+var ErrInvalidBackup = errors.New("invalid backup format")
+
+// VerifyBackup decodes a backup stream chunk by chunk to verify that the stream is not corrupted,
+// without loading it into a database. Only a single chunk is kept in memory. Number of keys
+// contained in the backup is returned. The stream is rewound before and after the verification.
+func VerifyBackup(r io.ReadSeeker) (int, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	keys, err := verifyBackupChunks(bufio.NewReader(r))
+	if err != nil {
+		return 0, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return keys, nil
+}
+
+// verifyBackupChunks reads the backup the same way badger's DB.Load does. Entries that would
+// not be visible once loaded are not counted.
+func verifyBackupChunks(r io.Reader) (int, error) {
+	now := uint64(time.Now().Unix())
+	keys := 0
+	buf := []byte{}
+	for {
+		var sz uint64
+		err := binary.Read(r, binary.LittleEndian, &sz)
+		if err == io.EOF {
+			return keys, nil
+		} else if err != nil {
+			return 0, fmt.Errorf("%w: %s", ErrInvalidBackup, err)
+		}
+		if sz > MaxBackupChunkSize {
+			return 0, fmt.Errorf("%w: chunk of %d bytes", ErrInvalidBackup, sz)
+		}
+		if uint64(cap(buf)) < sz {
+			buf = make([]byte, sz)
+		}
+		if _, err := io.ReadFull(r, buf[:sz]); err != nil {
+			return 0, fmt.Errorf("%w: %s", ErrInvalidBackup, err)
+		}
+
+		list := &pb.KVList{}
+		if err := proto.Unmarshal(buf[:sz], list); err != nil {
+			return 0, fmt.Errorf("%w: %s", ErrInvalidBackup, err)
+		}
+		for _, kv := range list.Kv {
+			if len(kv.Key) == 0 {
+				return 0, fmt.Errorf("%w: empty key", ErrInvalidBackup)
+			}
+			if kv.ExpiresAt != 0 && kv.ExpiresAt <= now {
+				continue
+			}
+			keys++
+		}
+	}
+}
+
+// CountKeys returns number of keys with the prefix.
+func CountKeys(db *badger.DB, prefix []byte) (int, error) {
+	keys := 0
+	err := db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = prefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			keys++
+		}
+		return nil
+	})
+	return keys, err
+}
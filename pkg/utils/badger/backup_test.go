@@ -0,0 +1,92 @@
+package badger_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/dgraph-io/badger/v2"
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// newBackup returns a backup of a database with n keys and one expired key.
+func newBackup(t *testing.T, n int) []byte {
+	db := openInMemory(t)
+	defer db.Close()
+
+	err := db.Update(func(txn *badger.Txn) error {
+		for i := 0; i < n; i++ {
+			if err := txn.Set([]byte(fmt.Sprintf("key.%d", i)), []byte("value")); err != nil {
+				return err
+			}
+		}
+		return txn.SetEntry(badger.NewEntry([]byte("expiring"), []byte("value")).WithTTL(time.Second))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if _, err := db.Backup(buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestVerifyBackup(t *testing.T) {
+	backup := newBackup(t, 100)
+	// The backup must be verified after the expiring key has expired.
+	time.Sleep(time.Second)
+
+	r := bytes.NewReader(backup)
+	keys, err := badgerutil.VerifyBackup(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 100, keys)
+
+	// The stream is rewound, so it can be loaded right away.
+	db := openInMemory(t)
+	defer db.Close()
+	if err := db.Load(r, badgerutil.LoadMaxPendingWrites); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := badgerutil.CountKeys(db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, keys, loaded)
+
+	keys, err = badgerutil.VerifyBackup(bytes.NewReader(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, keys)
+}
+
+func TestVerifyBackupTruncated(t *testing.T) {
+	backup := newBackup(t, 100)
+	for _, size := range []int{4, 8, 20, len(backup) - 1} {
+		_, err := badgerutil.VerifyBackup(bytes.NewReader(backup[:size]))
+		assert.True(t, errors.Is(err, badgerutil.ErrInvalidBackup), "size %d: %v", size, err)
+	}
+}
+
+func TestVerifyBackupGarbage(t *testing.T) {
+	garbage := bytes.Repeat([]byte{0xff}, 64)
+	_, err := badgerutil.VerifyBackup(bytes.NewReader(garbage))
+	assert.True(t, errors.Is(err, badgerutil.ErrInvalidBackup), err)
+
+	// A chunk of the right size that doesn't decode.
+	chunk := &bytes.Buffer{}
+	binary.Write(chunk, binary.LittleEndian, uint64(len(garbage)))
+	chunk.Write(garbage)
+	_, err = badgerutil.VerifyBackup(bytes.NewReader(chunk.Bytes()))
+	assert.True(t, errors.Is(err, badgerutil.ErrInvalidBackup), err)
+
+	// A huge chunk must be rejected before it's read.
+	chunk.Reset()
+	binary.Write(chunk, binary.LittleEndian, uint64(1<<40))
+	_, err = badgerutil.VerifyBackup(bytes.NewReader(chunk.Bytes()))
+	assert.True(t, errors.Is(err, badgerutil.ErrInvalidBackup), err)
+}
@@ -9,35 +9,60 @@ type Key []byte
 
 func Load(db *badger.DB, k Key, kv KVPairInterface) error {
 	return db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(k)
-		if err != nil {
-			return err
-		}
-		v, err := item.ValueCopy(nil)
-		if err != nil {
-			return err
-		}
-		kv.SetKey(k)
-		kv.SetMeta(item.UserMeta())
-		kv.SetExpires(time.Unix(int64(item.ExpiresAt()), 0))
-		if err := kv.SetValue(v); err != nil {
-			return err
-		}
-		return nil
+		return LoadTxn(txn, k, kv)
 	})
 }
 
 func Store(db *badger.DB, kv KVPairInterface) error {
 	return db.Update(func(txn *badger.Txn) error {
-		v, err := kv.Value()
-		if err != nil {
-			return err
-		}
-		return txn.SetEntry(&badger.Entry{
-			Key:       kv.Key(),
-			Value:     v,
-			UserMeta:  kv.Meta(),
-			ExpiresAt: uint64(kv.Expires().Unix()),
-		})
+		return StoreTxn(txn, kv)
 	})
 }
+
+// LoadTxn is like Load but reads the key within an existing transaction.
+func LoadTxn(txn *badger.Txn, k Key, kv KVPairInterface) error {
+	item, err := txn.Get(k)
+	if err != nil {
+		return err
+	}
+	v, err := item.ValueCopy(nil)
+	if err != nil {
+		return err
+	}
+	kv.SetKey(k)
+	kv.SetMeta(item.UserMeta())
+	kv.SetExpires(unixToTime(item.ExpiresAt()))
+	if err := kv.SetValue(v); err != nil {
+		return err
+	}
+	return nil
+}
+
+// StoreTxn is like Store but writes the key within an existing transaction.
+func StoreTxn(txn *badger.Txn, kv KVPairInterface) error {
+	v, err := kv.Value()
+	if err != nil {
+		return err
+	}
+	return txn.SetEntry(&badger.Entry{
+		Key:       kv.Key(),
+		Value:     v,
+		UserMeta:  kv.Meta(),
+		ExpiresAt: timeToUnix(kv.Expires()),
+	})
+}
+
+// timeToUnix converts t to a badger TTL. Zero time means the key never expires.
+func timeToUnix(t time.Time) uint64 {
+	if t.IsZero() || t.Unix() <= 0 {
+		return 0
+	}
+	return uint64(t.Unix())
+}
+
+func unixToTime(ts uint64) time.Time {
+	if ts == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(ts), 0)
+}
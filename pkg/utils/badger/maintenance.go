@@ -0,0 +1,142 @@
+package badger
+
+import (
+	"context"
+	"errors"
+	"github.com/dgraph-io/badger/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	gcResultRewritten = "rewritten"
+	gcResultNoRewrite = "no_rewrite"
+	gcResultError     = "error"
+)
+
+// Maintenance periodically runs garbage collection of the value log, which otherwise only grows.
+// Statistics of the database are exported to Prometheus.
+type Maintenance struct {
+	logger *zap.Logger
+	db     *badger.DB
+	// lock is held while garbage collection runs.
+	lock sync.Locker
+
+	interval     time.Duration
+	discardRatio float64
+
+	gcRuns    *prometheus.CounterVec
+	gcLastRun prometheus.Gauge
+	lsmSize   *prometheus.Desc
+	vlogSize  *prometheus.Desc
+	tables    *prometheus.Desc
+}
+
+func (m *Maintenance) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.runGC()
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// runGC rewrites value log files until there's nothing left to rewrite, as recommended by badger.
+func (m *Maintenance) runGC() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	defer m.gcLastRun.SetToCurrentTime()
+	for {
+		err := m.db.RunValueLogGC(m.discardRatio)
+		switch {
+		case err == nil:
+			m.gcRuns.WithLabelValues(gcResultRewritten).Inc()
+			continue
+		case errors.Is(err, badger.ErrNoRewrite):
+			m.gcRuns.WithLabelValues(gcResultNoRewrite).Inc()
+		case errors.Is(err, badger.ErrRejected):
+			// Another GC is running or the database is being closed.
+		default:
+			m.gcRuns.WithLabelValues(gcResultError).Inc()
+			m.logger.Error("value log GC failed", zap.Error(err))
+		}
+		return
+	}
+}
+
+func (m *Maintenance) Describe(ch chan<- *prometheus.Desc) {
+	m.gcRuns.Describe(ch)
+	m.gcLastRun.Describe(ch)
+	ch <- m.lsmSize
+	ch <- m.vlogSize
+	ch <- m.tables
+}
+
+func (m *Maintenance) Collect(ch chan<- prometheus.Metric) {
+	m.gcRuns.Collect(ch)
+	m.gcLastRun.Collect(ch)
+
+	lsm, vlog := m.db.Size()
+	ch <- prometheus.MustNewConstMetric(m.lsmSize, prometheus.GaugeValue, float64(lsm))
+	ch <- prometheus.MustNewConstMetric(m.vlogSize, prometheus.GaugeValue, float64(vlog))
+
+	tables := make(map[int]int)
+	for _, t := range m.db.Tables(false) {
+		tables[t.Level]++
+	}
+	for level, n := range tables {
+		ch <- prometheus.MustNewConstMetric(m.tables, prometheus.GaugeValue, float64(n), strconv.Itoa(level))
+	}
+}
+
+// NewMaintenance returns a maintenance component. Metrics are prefixed with namespace.
+// The lock is held during garbage collection, so that it doesn't run while the database is being replaced.
+func NewMaintenance(logger *zap.Logger, db *badger.DB, lock sync.Locker, namespace string, interval time.Duration, discardRatio float64) *Maintenance {
+	return &Maintenance{
+		logger:       logger,
+		db:           db,
+		lock:         lock,
+		interval:     interval,
+		discardRatio: discardRatio,
+		gcRuns: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: "badger",
+				Name:      "gc_runs_total",
+				Help:      "Number of value log GC runs by the result.",
+			},
+			[]string{"result"},
+		),
+		gcLastRun: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "badger",
+				Name:      "gc_last_run_timestamp_seconds",
+				Help:      "Time of the last value log GC.",
+			},
+		),
+		lsmSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "badger", "lsm_size_bytes"),
+			"Size of the LSM tree.",
+			nil, nil,
+		),
+		vlogSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "badger", "vlog_size_bytes"),
+			"Size of the value log.",
+			nil, nil,
+		),
+		tables: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "badger", "tables"),
+			"Number of tables in the LSM tree by level.",
+			[]string{"level"}, nil,
+		),
+	}
+}
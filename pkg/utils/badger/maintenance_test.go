@@ -0,0 +1,95 @@
+package badger
+
+import (
+	"github.com/dgraph-io/badger/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gather returns values of the metrics by their name and labels.
+func gather(t *testing.T, m *Maintenance) map[string]float64 {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(m)
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			name := family.GetName()
+			for _, label := range metric.GetLabel() {
+				name += "{" + label.GetName() + "=" + label.GetValue() + "}"
+			}
+			switch {
+			case metric.GetCounter() != nil:
+				values[name] = metric.GetCounter().GetValue()
+			case metric.GetGauge() != nil:
+				values[name] = metric.GetGauge().GetValue()
+			}
+		}
+	}
+	return values
+}
+
+func TestMaintenanceGC(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "badger-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m := NewMaintenance(zap.NewNop(), db, &sync.Mutex{}, "test", time.Minute, 0.5)
+	m.runGC()
+
+	values := gather(t, m)
+	// There's nothing to rewrite in a new database.
+	assert.Equal(t, float64(1), values["test_badger_gc_runs_total{result=no_rewrite}"])
+	assert.NotContains(t, values, "test_badger_gc_runs_total{result=rewritten}")
+	assert.NotContains(t, values, "test_badger_gc_runs_total{result=error}")
+	assert.InDelta(t, float64(time.Now().Unix()), values["test_badger_gc_last_run_timestamp_seconds"], 5)
+}
+
+func TestMaintenanceCollect(t *testing.T) {
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte("key"), []byte("value"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Tables are created only once the memtable is flushed, dropping a prefix flushes it.
+	if err := db.DropPrefix([]byte("nothing")); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMaintenance(zap.NewNop(), db, &sync.Mutex{}, "test", time.Minute, 0.5)
+	values := gather(t, m)
+	assert.Contains(t, values, "test_badger_lsm_size_bytes")
+	assert.Contains(t, values, "test_badger_vlog_size_bytes")
+
+	tables := 0.0
+	for name, v := range values {
+		if strings.HasPrefix(name, "test_badger_tables{") {
+			tables += v
+		}
+	}
+	assert.NotZero(t, tables)
+}
@@ -0,0 +1,89 @@
+package badger
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dgraph-io/badger/v2"
+	"strconv"
+)
+
+// SchemaVersionKey holds the version of the schema of the database. Databases created before
+// migrations were introduced have no version, which is the same as version 0.
+var SchemaVersionKey = Key("_schema.version")
+
+var ErrSchemaTooNew = errors.New("database schema is newer than supported")
+
+// Migration upgrades the database to Version. Migrations may be interrupted before the version is recorded,
+// therefore they must be safe to run again.
+type Migration struct {
+	Version     uint64
+	Description string
+	Migrate     func(db *badger.DB) error
+}
+
+// SchemaVersion returns the version of the schema of the database.
+func SchemaVersion(db *badger.DB) (uint64, error) {
+	version := uint64(0)
+	err := db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(SchemaVersionKey)
+		if err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return nil
+			}
+			return err
+		}
+		return item.Value(func(v []byte) error {
+			version, err = strconv.ParseUint(string(v), 10, 64)
+			return err
+		})
+	})
+	return version, err
+}
+
+func setSchemaVersion(db *badger.DB, version uint64) error {
+	return db.Update(func(txn *badger.Txn) error {
+		return txn.Set(SchemaVersionKey, []byte(strconv.FormatUint(version, 10)))
+	})
+}
+
+// Migrate runs migrations newer than the version of the database in order and records the version
+// after each of them. Migrations must be sorted by version. Applied migrations are returned, even on error.
+func Migrate(db *badger.DB, migrations []Migration) ([]Migration, error) {
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			return nil, fmt.Errorf("migration %d is out of order", migrations[i].Version)
+		}
+	}
+
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if len(migrations) > 0 && current > migrations[len(migrations)-1].Version {
+		return nil, ErrSchemaTooNew
+	}
+
+	applied := []Migration{}
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		if err := m.Migrate(db); err != nil {
+			return applied, fmt.Errorf("migration %d: %w", m.Version, err)
+		}
+		if err := setSchemaVersion(db, m.Version); err != nil {
+			return applied, err
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// Remigrate runs all migrations again. It's meant to be used after data of unknown schema version
+// were loaded into the database, for instance from a backup.
+func Remigrate(db *badger.DB, migrations []Migration) ([]Migration, error) {
+	if err := setSchemaVersion(db, 0); err != nil {
+		return nil, err
+	}
+	return Migrate(db, migrations)
+}
@@ -0,0 +1,149 @@
+package badger_test
+
+import (
+	"errors"
+	"github.com/dgraph-io/badger/v2"
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func openInMemory(t *testing.T) *badger.DB {
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func get(t *testing.T, db *badger.DB, k string) string {
+	v := ""
+	if err := db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(k))
+		if err != nil {
+			return err
+		}
+		b, err := item.ValueCopy(nil)
+		v = string(b)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// upperCase is a fixture migration, which upper-cases values of all keys with the prefix.
+func upperCase(prefix string) func(db *badger.DB) error {
+	return func(db *badger.DB) error {
+		return db.Update(func(txn *badger.Txn) error {
+			it := txn.NewIterator(badger.DefaultIteratorOptions)
+			defer it.Close()
+			for it.Seek([]byte(prefix)); it.ValidForPrefix([]byte(prefix)); it.Next() {
+				v, err := it.Item().ValueCopy(nil)
+				if err != nil {
+					return err
+				}
+				if err := txn.Set(it.Item().KeyCopy(nil), []byte(strings.ToUpper(string(v)))); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	db := openInMemory(t)
+	defer db.Close()
+
+	// A database created before migrations were introduced.
+	if err := db.Update(func(txn *badger.Txn) error {
+		if err := txn.Set([]byte("a.1"), []byte("one")); err != nil {
+			return err
+		}
+		return txn.Set([]byte("b.1"), []byte("two"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	version, err := badgerutil.SchemaVersion(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint64(0), version)
+
+	migrations := []badgerutil.Migration{
+		{Version: 1, Description: "upper-case a", Migrate: upperCase("a.")},
+	}
+	applied, err := badgerutil.Migrate(db, migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, applied, 1)
+	assert.Equal(t, "ONE", get(t, db, "a.1"))
+	assert.Equal(t, "two", get(t, db, "b.1"))
+
+	// Only new migrations are applied.
+	migrations = append(migrations, badgerutil.Migration{
+		Version: 2, Description: "upper-case b", Migrate: upperCase("b."),
+	})
+	applied, err = badgerutil.Migrate(db, migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, applied, 1)
+	assert.Equal(t, uint64(2), applied[0].Version)
+	assert.Equal(t, "TWO", get(t, db, "b.1"))
+
+	version, err = badgerutil.SchemaVersion(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint64(2), version)
+
+	applied, err = badgerutil.Migrate(db, migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, applied)
+}
+
+func TestMigrateFailure(t *testing.T) {
+	db := openInMemory(t)
+	defer db.Close()
+
+	failure := errors.New("failure")
+	migrations := []badgerutil.Migration{
+		{Version: 1, Migrate: func(db *badger.DB) error { return nil }},
+		{Version: 2, Migrate: func(db *badger.DB) error { return failure }},
+	}
+	applied, err := badgerutil.Migrate(db, migrations)
+	assert.True(t, errors.Is(err, failure))
+	assert.Len(t, applied, 1)
+
+	// The failed migration is run again next time.
+	version, err := badgerutil.SchemaVersion(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint64(1), version)
+}
+
+func TestMigrateSchemaTooNew(t *testing.T) {
+	db := openInMemory(t)
+	defer db.Close()
+
+	migrations := []badgerutil.Migration{
+		{Version: 1, Migrate: func(db *badger.DB) error { return nil }},
+		{Version: 2, Migrate: func(db *badger.DB) error { return nil }},
+	}
+	if _, err := badgerutil.Migrate(db, migrations); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := badgerutil.Migrate(db, migrations[:1])
+	assert.Equal(t, badgerutil.ErrSchemaTooNew, err)
+
+	_, err = badgerutil.Migrate(db, []badgerutil.Migration{migrations[1], migrations[0]})
+	assert.Error(t, err)
+}
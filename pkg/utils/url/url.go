@@ -1,8 +1,11 @@
 package url
 
 import (
+	"bytes"
+	"encoding/base32"
 	"errors"
 	"fmt"
+	"golang.org/x/crypto/sha3"
 	"net/url"
 	"strings"
 )
@@ -39,3 +42,94 @@ func ParseHostPort(u string) (string, error) {
 	}
 	return host, nil
 }
+
+var ErrInvalidOnion = errors.New("invalid onion address")
+
+// NormalizeAddress returns an address of a service in a form, which doesn't depend on the scheme,
+// letter case or a trailing dot of the host. The port is kept only if it's not the default port of the scheme.
+// Onion addresses are validated.
+func NormalizeAddress(u string) (string, error) {
+	r, err := url.Parse(strings.TrimSpace(u))
+	if err != nil {
+		return "", err
+	}
+	scheme := strings.ToLower(r.Scheme)
+	if scheme == "" || r.Host == "" {
+		return "", errors.New("invalid url format")
+	}
+	host := strings.TrimSuffix(strings.ToLower(r.Hostname()), ".")
+	if host == "" {
+		return "", errors.New("invalid url format")
+	}
+	if strings.HasSuffix(host, ".onion") {
+		if err := ValidateOnion(host); err != nil {
+			return "", err
+		}
+	}
+	port := r.Port()
+	if port == "" || port == defaultPort(scheme) {
+		return host, nil
+	}
+	return host + ":" + port, nil
+}
+
+// ValidateOnion checks that host is a valid v2 or v3 onion address. Subdomains are allowed.
+// Checksum of v3 addresses is verified.
+func ValidateOnion(host string) error {
+	labels := strings.Split(strings.TrimSuffix(strings.ToLower(host), "."), ".")
+	if len(labels) < 2 || labels[len(labels)-1] != "onion" {
+		return ErrInvalidOnion
+	}
+	label := labels[len(labels)-2]
+
+	switch len(label) {
+	case onionV2Length:
+		if _, err := onionEncoding.DecodeString(strings.ToUpper(label)); err != nil {
+			return ErrInvalidOnion
+		}
+		return nil
+	case onionV3Length:
+		return validateOnionV3(label)
+	}
+	return ErrInvalidOnion
+}
+
+const (
+	onionV2Length = 16
+	onionV3Length = 56
+
+	onionV3Version = 0x03
+)
+
+var onionEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// validateOnionV3 verifies the label encodes: PUBKEY (32 bytes) | CHECKSUM (2 bytes) | VERSION (1 byte),
+// where CHECKSUM is the beginning of SHA3-256(".onion checksum" | PUBKEY | VERSION).
+func validateOnionV3(label string) error {
+	b, err := onionEncoding.DecodeString(strings.ToUpper(label))
+	if err != nil || len(b) != 35 {
+		return ErrInvalidOnion
+	}
+	pubkey, checksum, version := b[:32], b[32:34], b[34]
+	if version != onionV3Version {
+		return ErrInvalidOnion
+	}
+	h := sha3.New256()
+	h.Write([]byte(".onion checksum"))
+	h.Write(pubkey)
+	h.Write([]byte{version})
+	if !bytes.Equal(h.Sum(nil)[:2], checksum) {
+		return ErrInvalidOnion
+	}
+	return nil
+}
+
+func defaultPort(scheme string) string {
+	switch scheme {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
@@ -0,0 +1,42 @@
+package url_test
+
+import (
+	urlutil "github.com/onionltd/mono/pkg/utils/url"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalizeAddress(t *testing.T) {
+	const onion = "duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad.onion"
+	valid := map[string]string{
+		"http://" + onion:           onion,
+		"https://" + onion:          onion,
+		"http://" + onion + ":80":   onion,
+		"https://" + onion + ":443": onion,
+		"HTTP://DUCKDUCKGOGG42XJOC72X3SJASOWOARFBGCMVFIMAFTT6TWAGSWZCZAD.ONION": onion,
+		"http://" + onion + ".":         onion,
+		"http://" + onion + ":8080":     onion + ":8080",
+		"http://www." + onion:           "www." + onion,
+		"http://expyuzz4wqqyqhjn.onion": "expyuzz4wqqyqhjn.onion",
+		"http://example.com:80":         "example.com",
+	}
+	for in, expected := range valid {
+		out, err := urlutil.NormalizeAddress(in)
+		if assert.NoError(t, err, in) {
+			assert.Equal(t, expected, out, in)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"/path",
+		"http://",
+		// Broken checksum.
+		"http://duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczaa.onion",
+		"http://tooshort.onion",
+	}
+	for _, in := range invalid {
+		_, err := urlutil.NormalizeAddress(in)
+		assert.Error(t, err, in)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	urlutil "github.com/onionltd/mono/pkg/utils/url"
+	"github.com/oniontree-org/go-oniontree/scanner"
+	"sync"
+)
+
+// addressIndex maps normalized addresses of mirrors to services, so links can be matched
+// regardless of the scheme, port or letter case.
+type addressIndex struct {
+	sync.RWMutex
+	// Format: services[address] = serviceID
+	services map[string]string
+	// Different URLs of a mirror normalize to the same address, the address is indexed
+	// as long as any of the URLs is.
+	// Format: addresses[serviceID][address][url]
+	addresses map[string]map[string]map[string]struct{}
+}
+
+func (i *addressIndex) ReadEvents(ctx context.Context, inputCh <-chan scanner.Event, outputCh chan<- scanner.Event) error {
+	defer func() {
+		if outputCh != nil {
+			close(outputCh)
+		}
+	}()
+
+	for {
+		select {
+		case event, more := <-inputCh:
+			if !more {
+				return nil
+			}
+
+			switch e := event.(type) {
+			case scanner.ScanEvent:
+				i.add(e.ServiceID, e.URL)
+
+			case scanner.WorkerStopped:
+				i.delete(e.ServiceID, e.URL)
+
+			case scanner.ProcessStopped:
+				i.deleteService(e.ServiceID)
+			}
+
+			if outputCh != nil {
+				outputCh <- event
+			}
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// GetServiceID returns ID of the service the address belongs to. The address must be normalized
+// with urlutil.NormalizeAddress.
+func (i *addressIndex) GetServiceID(addr string) (string, bool) {
+	i.RLock()
+	defer i.RUnlock()
+	serviceID, ok := i.services[addr]
+	return serviceID, ok
+}
+
+func (i *addressIndex) add(serviceID, url string) {
+	addr, err := urlutil.NormalizeAddress(url)
+	if err != nil {
+		return
+	}
+	i.Lock()
+	defer i.Unlock()
+	i.services[addr] = serviceID
+	if _, ok := i.addresses[serviceID]; !ok {
+		i.addresses[serviceID] = make(map[string]map[string]struct{})
+	}
+	if _, ok := i.addresses[serviceID][addr]; !ok {
+		i.addresses[serviceID][addr] = make(map[string]struct{})
+	}
+	i.addresses[serviceID][addr][url] = struct{}{}
+}
+
+func (i *addressIndex) delete(serviceID, url string) {
+	addr, err := urlutil.NormalizeAddress(url)
+	if err != nil {
+		return
+	}
+	i.Lock()
+	defer i.Unlock()
+	urls, ok := i.addresses[serviceID][addr]
+	if !ok {
+		return
+	}
+	delete(urls, url)
+	if len(urls) > 0 {
+		return
+	}
+	if i.services[addr] == serviceID {
+		delete(i.services, addr)
+	}
+	delete(i.addresses[serviceID], addr)
+}
+
+func (i *addressIndex) deleteService(serviceID string) {
+	i.Lock()
+	defer i.Unlock()
+	for addr := range i.addresses[serviceID] {
+		if i.services[addr] == serviceID {
+			delete(i.services, addr)
+		}
+	}
+	delete(i.addresses, serviceID)
+}
+
+func newAddressIndex() *addressIndex {
+	return &addressIndex{
+		services:  make(map[string]string),
+		addresses: make(map[string]map[string]map[string]struct{}),
+	}
+}
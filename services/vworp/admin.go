@@ -0,0 +1,96 @@
+package main
+
+import (
+	"github.com/dgraph-io/badger/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/onionltd/mono/services/vworp/badger/links"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+)
+
+const (
+	adminLinksDefaultLimit = 50
+	adminLinksMaxLimit     = 500
+)
+
+// listServiceLinks returns a page of links of the service and a fingerprint to continue from,
+// which is empty on the last page.
+func (s *server) listServiceLinks(c echo.Context) ([]*links.Link, string, int) {
+	limit := adminLinksDefaultLimit
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, "", http.StatusBadRequest
+		}
+		if n > adminLinksMaxLimit {
+			n = adminLinksMaxLimit
+		}
+		limit = n
+	}
+
+	var result []*links.Link
+	err := s.badgerDB.View(func(txn *badger.Txn) error {
+		var err error
+		// One more link is loaded to find out if there's a next page.
+		result, err = links.ListByServiceTxn(txn, c.Param("id"), c.QueryParam("after"), limit+1)
+		return err
+	})
+	if err != nil {
+		s.logger.Error("failed to list links", zap.Error(err))
+		return nil, "", http.StatusInternalServerError
+	}
+
+	next := ""
+	if len(result) > limit {
+		result = result[:limit]
+		next = result[limit-1].Fingerprint()
+	}
+	return result, next, http.StatusOK
+}
+
+func (s *server) handleAdminServiceLinks() echo.HandlerFunc {
+	type pageData struct {
+		ServiceID string
+		Links     []*links.Link
+		// Next is a fingerprint to continue listing after, empty on the last page.
+		Next  string
+		Limit string
+	}
+	return func(c echo.Context) error {
+		result, next, code := s.listServiceLinks(c)
+		if code != http.StatusOK {
+			return c.String(code, http.StatusText(code))
+		}
+
+		pageContent := pageData{}
+		pageContent.ServiceID = c.Param("id")
+		pageContent.Links = result
+		pageContent.Next = next
+		pageContent.Limit = c.QueryParam("limit")
+		return c.Render(http.StatusOK, "admin_links", pageContent)
+	}
+}
+
+func (s *server) handleAdminServiceLinksJSON() echo.HandlerFunc {
+	type response struct {
+		Links []apiLink `json:"links"`
+		// Next is a fingerprint to continue listing after, empty on the last page.
+		Next string `json:"next,omitempty"`
+	}
+	return func(c echo.Context) error {
+		result, next, code := s.listServiceLinks(c)
+		if code != http.StatusOK {
+			return apiError(c, code)
+		}
+
+		resp := response{
+			Links: make([]apiLink, 0, len(result)),
+			Next:  next,
+		}
+		for _, link := range result {
+			resp.Links = append(resp.Links, newAPILink(c, link))
+		}
+		return c.JSON(http.StatusOK, resp)
+	}
+}
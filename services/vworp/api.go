@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/onionltd/mono/services/vworp/badger/links"
+	"github.com/onionltd/mono/services/vworp/badger/stats"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+type apiLink struct {
+	Fingerprint string     `json:"fingerprint"`
+	ServiceID   string     `json:"service_id"`
+	Path        string     `json:"path"`
+	Slug        string     `json:"slug,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	URL         string     `json:"url"`
+	// Shared is true if someone else submitted the link too, only its first submitter can manage it.
+	Shared bool   `json:"shared"`
+	Token  string `json:"token,omitempty"`
+}
+
+func newAPILink(c echo.Context, link *links.Link) apiLink {
+	l := apiLink{
+		Fingerprint: link.Fingerprint(),
+		ServiceID:   link.ServiceID(),
+		Path:        link.Path(),
+		Slug:        link.Slug(),
+		Shared:      link.Shared(),
+		URL:         fmt.Sprintf("http://%s/to/%s/%s", c.Request().Host, link.ServiceID(), link.Fingerprint()),
+	}
+	if expiresAt := link.ExpiresAt(); !expiresAt.IsZero() {
+		l.ExpiresAt = &expiresAt
+	}
+	return l
+}
+
+func apiError(c echo.Context, code int) error {
+	return c.JSON(code, map[string]interface{}{
+		"error": http.StatusText(code),
+	})
+}
+
+func (s *server) handleAPILinksCreate() echo.HandlerFunc {
+	type request struct {
+		Link    string `json:"link" form:"link"`
+		Expires string `json:"expires" form:"expires"`
+		Slug    string `json:"slug" form:"slug"`
+	}
+	return func(c echo.Context) error {
+		req := request{}
+		if err := c.Bind(&req); err != nil {
+			return apiError(c, http.StatusBadRequest)
+		}
+
+		link, token, code := s.createLink("", req.Link, req.Expires, req.Slug)
+		if code != http.StatusOK {
+			return apiError(c, code)
+		}
+		resp := newAPILink(c, link)
+		resp.Token = token
+		return c.JSON(http.StatusCreated, resp)
+	}
+}
+
+func (s *server) handleAPILinksGet() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		link, code := s.loadLink(c.Param("fp"))
+		if code != http.StatusOK {
+			return apiError(c, code)
+		}
+		return c.JSON(http.StatusOK, newAPILink(c, link))
+	}
+}
+
+func (s *server) handleAPILinksResolve() echo.HandlerFunc {
+	type response struct {
+		Online bool   `json:"online"`
+		URL    string `json:"url,omitempty"`
+	}
+	return func(c echo.Context) error {
+		link, code := s.loadLink(c.Param("fp"))
+		if code != http.StatusOK {
+			return apiError(c, code)
+		}
+
+		if _, err := s.ot.GetService(link.ServiceID()); err != nil {
+			return apiError(c, http.StatusNotFound)
+		}
+
+		if s.blocks.BlockedLink(link) {
+			return apiError(c, http.StatusUnavailableForLegalReasons)
+		}
+
+		resp := response{}
+		if mirror := s.selectMirror(link.ServiceID()); mirror != "" {
+			resp.Online = true
+			resp.URL = mirror + link.Path()
+		}
+		return c.JSON(http.StatusOK, resp)
+	}
+}
+
+func (s *server) handleAPILinksStats() echo.HandlerFunc {
+	type day struct {
+		Date  string `json:"date"`
+		Count uint64 `json:"count"`
+	}
+	type response struct {
+		Total uint64 `json:"total"`
+		Days  []day  `json:"days"`
+	}
+	return func(c echo.Context) error {
+		link, code := s.loadLink(c.Param("fp"))
+		if code != http.StatusOK {
+			return apiError(c, code)
+		}
+
+		days, err := s.loadStats(link)
+		if err != nil {
+			s.logger.Error("failed to load stats", zap.Error(err))
+			return apiError(c, http.StatusInternalServerError)
+		}
+
+		resp := response{
+			Total: stats.Total(days),
+			Days:  make([]day, 0, len(days)),
+		}
+		for _, d := range days {
+			resp.Days = append(resp.Days, day{
+				Date:  d.Day().Format("2006-01-02"),
+				Count: d.Count(),
+			})
+		}
+		return c.JSON(http.StatusOK, resp)
+	}
+}
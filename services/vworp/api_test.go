@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"github.com/onionltd/mono/services/vworp/badger/links"
+	"github.com/onionltd/mono/services/vworp/badger/moderation"
+	"github.com/oniontree-org/go-oniontree/scanner"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAPIKey = "secret"
+
+// apiRequest sends a request to the JSON API and decodes the response to v, unless it's nil.
+func apiRequest(t *testing.T, s *testServer, method, target, body string, v interface{}) int {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+testAPIKey)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s %s: %s", method, target, err)
+		}
+	}
+	return rec.Code
+}
+
+func TestAPILinksCreate(t *testing.T) {
+	s := newTestServer(t, "--api-auth", testAPIKey)
+
+	created := apiLink{}
+	code := apiRequest(t, s, http.MethodPost, "/api/v1/links", `{"link": "`+testMirror+`/page", "slug": "my-page"}`, &created)
+	if !assert.Equal(t, http.StatusCreated, code) {
+		t.FailNow()
+	}
+	assert.Equal(t, testServiceID, created.ServiceID)
+	assert.Equal(t, "/page", created.Path)
+	assert.Equal(t, "my-page", created.Slug)
+	assert.Nil(t, created.ExpiresAt)
+	assert.NotEmpty(t, created.Token)
+	assert.False(t, created.Shared)
+	assert.True(t, strings.HasSuffix(created.URL, "/to/"+testServiceID+"/"+created.Fingerprint))
+
+	// The same link is shared, without the token.
+	again := apiLink{}
+	code = apiRequest(t, s, http.MethodPost, "/api/v1/links", `{"link": "`+testMirror+`/page"}`, &again)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, created.Fingerprint, again.Fingerprint)
+	assert.Empty(t, again.Token)
+	assert.True(t, again.Shared)
+
+	for body, expected := range map[string]int{
+		`{"link": "not a link"}`:                               http.StatusBadRequest,
+		`{"link": "http://unknown.onion/page"}`:                http.StatusBadRequest,
+		`{"link": "` + testMirror + `/page", "expires": "1y"}`: http.StatusBadRequest,
+		`{"link": "` + testMirror + `/page", "slug": "-"}`:     http.StatusUnprocessableEntity,
+		`{"link": "http://example.com/page"}`:                  http.StatusNotFound,
+		`{"link": `:                                            http.StatusBadRequest,
+	} {
+		response := map[string]string{}
+		code := apiRequest(t, s, http.MethodPost, "/api/v1/links", body, &response)
+		assert.Equal(t, expected, code, body)
+		assert.Equal(t, http.StatusText(expected), response["error"], body)
+	}
+
+	// The API is protected with the key.
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/links", strings.NewReader(`{"link": "`+testMirror+`/other"}`))
+	req.Header.Set(echo.HeaderAuthorization, "Bearer wrong")
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestAPILinksGet(t *testing.T) {
+	s := newTestServer(t, "--api-auth", testAPIKey)
+	link := s.storeLink(t, "/page", "my-page")
+
+	for _, id := range []string{link.Fingerprint(), "my-page"} {
+		got := apiLink{}
+		code := apiRequest(t, s, http.MethodGet, "/api/v1/links/"+id, "", &got)
+		if assert.Equal(t, http.StatusOK, code, id) {
+			assert.Equal(t, link.Fingerprint(), got.Fingerprint, id)
+			assert.Equal(t, "/page", got.Path, id)
+			assert.Equal(t, "my-page", got.Slug, id)
+			assert.NotNil(t, got.ExpiresAt, id)
+			assert.Empty(t, got.Token, id)
+		}
+	}
+	assertAPIErrors(t, s, "")
+}
+
+func TestAPILinksResolve(t *testing.T) {
+	s := newTestServer(t, "--api-auth", testAPIKey)
+	link := s.storeLink(t, "/page", "my-page")
+
+	type response struct {
+		Online bool   `json:"online"`
+		URL    string `json:"url"`
+	}
+	s.setMirrorStatus(scanner.StatusOffline)
+	resolved := response{}
+	code := apiRequest(t, s, http.MethodGet, "/api/v1/links/"+link.Fingerprint()+"/resolve", "", &resolved)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, response{}, resolved)
+
+	s.setMirrorStatus(scanner.StatusOnline)
+	for _, id := range []string{link.Fingerprint(), "my-page"} {
+		resolved := response{}
+		code := apiRequest(t, s, http.MethodGet, "/api/v1/links/"+id+"/resolve", "", &resolved)
+		assert.Equal(t, http.StatusOK, code, id)
+		assert.Equal(t, response{Online: true, URL: testMirror + "/page"}, resolved, id)
+	}
+	assertAPIErrors(t, s, "/resolve")
+}
+
+func TestAPILinksStats(t *testing.T) {
+	s := newTestServer(t, "--api-auth", testAPIKey)
+	link := s.storeLink(t, "/page", "my-page")
+
+	type response struct {
+		Total uint64 `json:"total"`
+		Days  []struct {
+			Date  string `json:"date"`
+			Count uint64 `json:"count"`
+		} `json:"days"`
+	}
+	empty := response{}
+	code := apiRequest(t, s, http.MethodGet, "/api/v1/links/"+link.Fingerprint()+"/stats", "", &empty)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, uint64(0), empty.Total)
+	assert.Empty(t, empty.Days)
+
+	s.countRedirect(link)
+	s.countRedirect(link)
+	for _, id := range []string{link.Fingerprint(), "my-page"} {
+		counted := response{}
+		code := apiRequest(t, s, http.MethodGet, "/api/v1/links/"+id+"/stats", "", &counted)
+		if assert.Equal(t, http.StatusOK, code, id) && assert.Len(t, counted.Days, 1, id) {
+			assert.Equal(t, uint64(2), counted.Total, id)
+			assert.Equal(t, uint64(2), counted.Days[0].Count, id)
+			assert.Equal(t, time.Now().UTC().Format("2006-01-02"), counted.Days[0].Date, id)
+		}
+	}
+	assertAPIErrors(t, s, "/stats")
+
+	// Statistics can be disabled.
+	s = newTestServer(t, "--api-auth", testAPIKey, "--no-stats")
+	link = s.storeLink(t, "/page", "")
+	code = apiRequest(t, s, http.MethodGet, "/api/v1/links/"+link.Fingerprint()+"/stats", "", nil)
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+// assertAPIErrors checks responses of an endpoint under /api/v1/links/:fp to unknown, expired
+// and blocked links.
+func assertAPIErrors(t *testing.T, s *testServer, suffix string) {
+	expired, err := links.NewLink(testServiceID, "/expired")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired.SetExpiresAt(time.Now().Add(-time.Hour))
+	if err := links.Store(s.badgerDB, expired); err != nil {
+		t.Fatal(err)
+	}
+
+	blocked := s.storeLink(t, "/blocked", "")
+	block, err := moderation.NewBlock(moderation.KindFingerprint, blocked.Fingerprint(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := moderation.Add(s.badgerDB, block); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.blocks.Load(s.badgerDB); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, expected := range map[string]int{
+		"aaaaa":               http.StatusNotFound,
+		"unknown-slug":        http.StatusNotFound,
+		expired.Fingerprint(): http.StatusGone,
+		blocked.Fingerprint(): http.StatusUnavailableForLegalReasons,
+	} {
+		response := map[string]string{}
+		code := apiRequest(t, s, http.MethodGet, "/api/v1/links/"+id+suffix, "", &response)
+		assert.Equal(t, expected, code, id+suffix)
+		assert.Equal(t, http.StatusText(expected), response["error"], id+suffix)
+	}
+}
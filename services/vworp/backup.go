@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dgraph-io/badger/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	backupTypeFull        = "full"
+	backupTypeIncremental = "incremental"
+
+	backupManifestFilename = "manifest.json"
+)
+
+type backupManifestEntry struct {
+	Filename  string    `json:"filename"`
+	Type      string    `json:"type"`
+	Since     uint64    `json:"since"`
+	Version   uint64    `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// backupManifest lists backups in the order they were created. A full backup followed by its
+// incremental backups form a chain, which must be restored in order.
+type backupManifest struct {
+	Backups []backupManifestEntry `json:"backups"`
+}
+
+func (m backupManifest) last() (backupManifestEntry, bool) {
+	if len(m.Backups) == 0 {
+		return backupManifestEntry{}, false
+	}
+	return m.Backups[len(m.Backups)-1], true
+}
+
+func (m backupManifest) lastFull() (backupManifestEntry, bool) {
+	for i := len(m.Backups) - 1; i >= 0; i-- {
+		if m.Backups[i].Type == backupTypeFull {
+			return m.Backups[i], true
+		}
+	}
+	return backupManifestEntry{}, false
+}
+
+// backupScheduler periodically writes full and incremental backups of the database to a local directory.
+type backupScheduler struct {
+	logger *zap.Logger
+	db     *badger.DB
+	// lock is held while a backup is being written.
+	lock sync.Locker
+
+	dir                 string
+	fullInterval        time.Duration
+	incrementalInterval time.Duration
+	// retention specifies how many backup chains are kept.
+	retention int
+
+	lastSuccess *prometheus.GaugeVec
+	lastFailure *prometheus.GaugeVec
+}
+
+func (b *backupScheduler) Run(ctx context.Context) error {
+	if err := os.MkdirAll(b.dir, 0700); err != nil {
+		return err
+	}
+
+	t := time.Duration(0)
+	for {
+		select {
+		case <-time.After(t):
+			if err := b.backup(); err != nil {
+				b.logger.Error("scheduled backup failed", zap.Error(err))
+			}
+			t = b.incrementalInterval
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (b *backupScheduler) backup() (err error) {
+	// A full backup is due if the manifest can't be read.
+	backupType := backupTypeFull
+	defer func() {
+		if err != nil {
+			b.lastFailure.WithLabelValues(backupType).SetToCurrentTime()
+			return
+		}
+		b.lastSuccess.WithLabelValues(backupType).SetToCurrentTime()
+	}()
+
+	manifest, err := b.readManifest()
+	if err != nil {
+		return err
+	}
+
+	since := uint64(0)
+	full, ok := manifest.lastFull()
+	if ok && time.Since(full.CreatedAt) < b.fullInterval {
+		backupType = backupTypeIncremental
+		last, _ := manifest.last()
+		since = last.Version + 1
+	}
+
+	entry, err := b.writeBackup(backupType, since)
+	if err != nil {
+		return err
+	}
+
+	// Nothing has changed since the last backup.
+	if entry == nil {
+		return nil
+	}
+
+	manifest.Backups = append(manifest.Backups, *entry)
+	manifest, expired := b.rotate(manifest)
+	if err := b.writeManifest(manifest); err != nil {
+		// The backup is not listed in the manifest, it would never be rotated.
+		os.Remove(filepath.Join(b.dir, entry.Filename))
+		return err
+	}
+	// Old backups are removed only once the manifest doesn't list them.
+	b.remove(expired)
+
+	b.logger.Info("backup created",
+		zap.String("filename", entry.Filename),
+		zap.String("type", entry.Type),
+		zap.Uint64("version", entry.Version),
+	)
+	return nil
+}
+
+// writeBackup writes a backup of entries newer than since. If there are no such entries, nil is returned.
+func (b *backupScheduler) writeBackup(backupType string, since uint64) (*backupManifestEntry, error) {
+	now := time.Now()
+
+	f, err := ioutil.TempFile(b.dir, ".backup")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	b.lock.Lock()
+	version, err := b.db.Backup(f, since)
+	b.lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// DB.Backup returns zero version if no entries were written.
+	if version == 0 {
+		return nil, nil
+	}
+
+	if err := f.Sync(); err != nil {
+		return nil, err
+	}
+	filename := fmt.Sprintf("backup-%s-%s-%d.dat", now.Format("2006-01-02_150405"), backupType, version)
+	if err := os.Rename(f.Name(), filepath.Join(b.dir, filename)); err != nil {
+		return nil, err
+	}
+	return &backupManifestEntry{
+		Filename:  filename,
+		Type:      backupType,
+		Since:     since,
+		Version:   version,
+		CreatedAt: now,
+	}, nil
+}
+
+// rotate drops backup chains exceeding the retention from the manifest. The dropped backups are returned.
+func (b *backupScheduler) rotate(manifest backupManifest) (backupManifest, []backupManifestEntry) {
+	chains := 0
+	for i := len(manifest.Backups) - 1; i >= 0; i-- {
+		if manifest.Backups[i].Type != backupTypeFull {
+			continue
+		}
+		chains++
+		if chains < b.retention {
+			continue
+		}
+		kept := make(map[string]struct{})
+		for _, entry := range manifest.Backups[i:] {
+			kept[entry.Filename] = struct{}{}
+		}
+		expired := []backupManifestEntry{}
+		for _, entry := range manifest.Backups[:i] {
+			if _, ok := kept[entry.Filename]; !ok {
+				expired = append(expired, entry)
+			}
+		}
+		manifest.Backups = manifest.Backups[i:]
+		return manifest, expired
+	}
+	return manifest, nil
+}
+
+// remove removes files of the backups.
+func (b *backupScheduler) remove(entries []backupManifestEntry) {
+	for _, entry := range entries {
+		if err := os.Remove(filepath.Join(b.dir, entry.Filename)); err != nil && !os.IsNotExist(err) {
+			b.logger.Warn("failed to remove an old backup", zap.Error(err))
+		}
+	}
+}
+
+func (b *backupScheduler) readManifest() (backupManifest, error) {
+	manifest := backupManifest{}
+	data, err := ioutil.ReadFile(filepath.Join(b.dir, backupManifestFilename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return manifest, nil
+		}
+		return manifest, err
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, err
+	}
+	return manifest, nil
+}
+
+func (b *backupScheduler) writeManifest(manifest backupManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(b.dir, "."+backupManifestFilename)
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(b.dir, backupManifestFilename))
+}
+
+func (b *backupScheduler) Describe(ch chan<- *prometheus.Desc) {
+	b.lastSuccess.Describe(ch)
+	b.lastFailure.Describe(ch)
+}
+
+func (b *backupScheduler) Collect(ch chan<- prometheus.Metric) {
+	b.lastSuccess.Collect(ch)
+	b.lastFailure.Collect(ch)
+}
+
+func newBackupScheduler(logger *zap.Logger, db *badger.DB, lock sync.Locker, cfg *config) *backupScheduler {
+	return &backupScheduler{
+		logger:              logger,
+		db:                  db,
+		lock:                lock,
+		dir:                 cfg.BackupDir,
+		fullInterval:        cfg.BackupFullInterval,
+		incrementalInterval: cfg.BackupIncrementalInterval,
+		retention:           cfg.BackupRetention,
+		lastSuccess: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: "vworp",
+				Subsystem: "backup",
+				Name:      "last_success_timestamp_seconds",
+				Help:      "Time of the last successful scheduled backup.",
+			},
+			[]string{"type"},
+		),
+		lastFailure: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: "vworp",
+				Subsystem: "backup",
+				Name:      "last_failure_timestamp_seconds",
+				Help:      "Time of the last failed scheduled backup.",
+			},
+			[]string{"type"},
+		),
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	badger "github.com/dgraph-io/badger/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestBackupScheduler(t *testing.T, retention int) *backupScheduler {
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tempDir) })
+
+	db, err := badger.Open(badger.DefaultOptions(filepath.Join(tempDir, "badgerdb")).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	cfg := &config{
+		BackupDir:                 filepath.Join(tempDir, "backups"),
+		BackupFullInterval:        time.Hour,
+		BackupIncrementalInterval: time.Minute,
+		BackupRetention:           retention,
+	}
+	b := newBackupScheduler(zap.NewNop(), db, &sync.Mutex{}, cfg)
+	if err := os.MkdirAll(b.dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func setKey(t *testing.T, db *badger.DB, key string) {
+	err := db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(key), []byte("value"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// backupFiles returns names of backups in the directory, sorted.
+func backupFiles(t *testing.T, b *backupScheduler) []string {
+	files, err := filepath.Glob(filepath.Join(b.dir, "backup-*.dat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range files {
+		files[i] = filepath.Base(files[i])
+	}
+	sort.Strings(files)
+	return files
+}
+
+// manifestFiles returns names of backups in the manifest, sorted.
+func manifestFiles(t *testing.T, b *backupScheduler) []string {
+	manifest, err := b.readManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []string{}
+	for _, entry := range manifest.Backups {
+		files = append(files, entry.Filename)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// restoreKeys loads backups into an empty database and returns its keys.
+func restoreKeys(t *testing.T, b *backupScheduler, filenames ...string) []string {
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, filename := range filenames {
+		data, err := ioutil.ReadFile(filepath.Join(b.dir, filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Load(bytes.NewReader(data), 16); err != nil {
+			t.Fatal(err)
+		}
+	}
+	keys := []string{}
+	err = db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			keys = append(keys, string(it.Item().Key()))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+// lastFailure returns time of the last failed backup of the type, zero if there's none.
+func lastFailure(t *testing.T, b *backupScheduler, backupType string) float64 {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(b)
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != "vworp_backup_last_failure_timestamp_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "type" && label.GetValue() == backupType {
+					return metric.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestBackupSchedulerIncremental(t *testing.T) {
+	b := newTestBackupScheduler(t, 2)
+
+	setKey(t, b.db, "first")
+	if err := b.backup(); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := b.readManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Len(t, manifest.Backups, 1) {
+		t.FailNow()
+	}
+	full := manifest.Backups[0]
+	assert.Equal(t, backupTypeFull, full.Type)
+	assert.Equal(t, uint64(0), full.Since)
+	assert.Equal(t, []string{full.Filename}, backupFiles(t, b))
+
+	// Nothing has changed, no backup is written.
+	if err := b.backup(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{full.Filename}, manifestFiles(t, b))
+
+	setKey(t, b.db, "second")
+	if err := b.backup(); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err = b.readManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Len(t, manifest.Backups, 2) {
+		t.FailNow()
+	}
+	incremental := manifest.Backups[1]
+	assert.Equal(t, backupTypeIncremental, incremental.Type)
+	assert.Equal(t, full.Version+1, incremental.Since)
+	assert.True(t, incremental.Version > full.Version)
+
+	// The incremental backup contains only the changes, the chain contains everything.
+	assert.Equal(t, []string{"second"}, restoreKeys(t, b, incremental.Filename))
+	assert.Equal(t, []string{"first", "second"}, restoreKeys(t, b, full.Filename, incremental.Filename))
+}
+
+func TestBackupSchedulerRotate(t *testing.T) {
+	b := newTestBackupScheduler(t, 2)
+
+	chains := [][]string{}
+	for i := 0; i < 3; i++ {
+		// Every backup is full.
+		b.fullInterval = 0
+		setKey(t, b.db, fmt.Sprintf("full.%d", i))
+		if err := b.backup(); err != nil {
+			t.Fatal(err)
+		}
+		b.fullInterval = time.Hour
+		setKey(t, b.db, fmt.Sprintf("incremental.%d", i))
+		if err := b.backup(); err != nil {
+			t.Fatal(err)
+		}
+		manifest, err := b.readManifest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		last := manifest.Backups[len(manifest.Backups)-2:]
+		assert.Equal(t, backupTypeFull, last[0].Type)
+		assert.Equal(t, backupTypeIncremental, last[1].Type)
+		chains = append(chains, []string{last[0].Filename, last[1].Filename})
+	}
+
+	// Only the last two chains are kept, both in the manifest and on the disk.
+	kept := append(append([]string{}, chains[1]...), chains[2]...)
+	sort.Strings(kept)
+	assert.Equal(t, kept, manifestFiles(t, b))
+	assert.Equal(t, kept, backupFiles(t, b))
+
+	// Backups listed in the manifest are not removed if the new manifest can't be written.
+	if err := os.Mkdir(filepath.Join(b.dir, "."+backupManifestFilename), 0700); err != nil {
+		t.Fatal(err)
+	}
+	b.fullInterval = 0
+	setKey(t, b.db, "full.3")
+	assert.Error(t, b.backup())
+	assert.Equal(t, kept, manifestFiles(t, b))
+	assert.Equal(t, kept, backupFiles(t, b))
+}
+
+func TestBackupSchedulerManifest(t *testing.T) {
+	b := newTestBackupScheduler(t, 2)
+
+	setKey(t, b.db, "first")
+	if err := b.backup(); err != nil {
+		t.Fatal(err)
+	}
+	kept := manifestFiles(t, b)
+
+	// The manifest is written to a temporary file first. If that fails, the old manifest is intact.
+	tmp := filepath.Join(b.dir, "."+backupManifestFilename)
+	if err := os.Mkdir(tmp, 0700); err != nil {
+		t.Fatal(err)
+	}
+	setKey(t, b.db, "second")
+	assert.Error(t, b.backup())
+	assert.NotZero(t, lastFailure(t, b, backupTypeIncremental))
+	assert.Equal(t, kept, manifestFiles(t, b))
+	assert.Equal(t, kept, backupFiles(t, b))
+
+	// A temporary file left behind by a crash is replaced.
+	if err := os.Remove(tmp); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(tmp, []byte("{\"backups\": ["), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.backup(); err != nil {
+		t.Fatal(err)
+	}
+	files := manifestFiles(t, b)
+	assert.Len(t, files, 2)
+	assert.Subset(t, files, kept)
+	assert.Equal(t, files, backupFiles(t, b))
+	_, err := os.Stat(tmp)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := config{
+		BackupDir:                 "/backups",
+		BackupFullInterval:        time.Hour,
+		BackupIncrementalInterval: time.Minute,
+		BackupRetention:           1,
+	}
+	assert.NoError(t, valid.validate())
+
+	for _, modify := range []func(c *config){
+		func(c *config) { c.BackupRetention = 0 },
+		func(c *config) { c.BackupRetention = -1 },
+		func(c *config) { c.BackupFullInterval = 0 },
+		func(c *config) { c.BackupIncrementalInterval = 0 },
+	} {
+		c := valid
+		modify(&c)
+		assert.Error(t, c.validate())
+
+		// Backups are disabled, the values don't matter.
+		c.BackupDir = ""
+		assert.NoError(t, c.validate())
+	}
+}
@@ -0,0 +1,66 @@
+package captchas
+
+import (
+	"fmt"
+	"github.com/onionltd/mono/pkg/utils/badger"
+	"strings"
+	"time"
+)
+
+// Captcha holds an answer to a captcha challenge.
+type Captcha struct {
+	id      string
+	answer  string
+	expires time.Time
+}
+
+func (c Captcha) ID() string {
+	return c.id
+}
+
+func (c Captcha) Answer() string {
+	return c.answer
+}
+
+// Methods to fulfill badger interface.
+func (c Captcha) Key() badger.Key {
+	return NewKey(c.id)
+}
+
+func (c *Captcha) SetKey(k badger.Key) {
+	tokens := strings.SplitN(string(k), ".", 2)
+	c.id = tokens[1]
+}
+
+func (c Captcha) Value() ([]byte, error) {
+	return []byte(c.answer), nil
+}
+
+func (c *Captcha) SetValue(v []byte) error {
+	c.answer = string(v)
+	return nil
+}
+
+func (c Captcha) Meta() byte { return 0 }
+
+func (c Captcha) SetMeta(m byte) {}
+
+func (c Captcha) Expires() time.Time { return c.expires }
+
+func (c *Captcha) SetExpires(t time.Time) { c.expires = t }
+
+func (c Captcha) Error() string { return "" }
+
+const keyPrefix = "captchas"
+
+func NewKey(id string) badger.Key {
+	return badger.Key(fmt.Sprintf("%s.%s", keyPrefix, id))
+}
+
+func NewCaptcha(id, answer string, expires time.Time) *Captcha {
+	return &Captcha{
+		id:      id,
+		answer:  answer,
+		expires: expires,
+	}
+}
@@ -0,0 +1,72 @@
+package captchas
+
+import (
+	"crypto/subtle"
+	"errors"
+	"github.com/dgraph-io/badger/v2"
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Store implements base64Captcha.Store on top of badger. Captchas expire after ttl
+// and can be verified only once.
+type Store struct {
+	logger *zap.Logger
+	db     *badger.DB
+	ttl    time.Duration
+}
+
+func (s *Store) Set(id string, value string) {
+	c := NewCaptcha(id, value, time.Now().Add(s.ttl))
+	if err := badgerutil.Store(s.db, c); err != nil {
+		s.logger.Error("failed to store captcha", zap.Error(err))
+	}
+}
+
+func (s *Store) Get(id string, clear bool) string {
+	if !clear {
+		c := &Captcha{}
+		if err := badgerutil.Load(s.db, NewKey(id), c); err != nil {
+			if !errors.Is(err, badger.ErrKeyNotFound) {
+				s.logger.Error("failed to load captcha", zap.Error(err))
+			}
+			return ""
+		}
+		return c.Answer()
+	}
+
+	answer := ""
+	err := s.db.Update(func(txn *badger.Txn) error {
+		c := &Captcha{}
+		if err := badgerutil.LoadTxn(txn, NewKey(id), c); err != nil {
+			return err
+		}
+		answer = c.Answer()
+		return txn.Delete(c.Key())
+	})
+	if err != nil {
+		if !errors.Is(err, badger.ErrKeyNotFound) {
+			// Concurrent attempts to use the same captcha end up here with ErrConflict.
+			s.logger.Warn("failed to load captcha", zap.Error(err))
+		}
+		return ""
+	}
+	return answer
+}
+
+func (s *Store) Verify(id, answer string, clear bool) bool {
+	v := s.Get(id, clear)
+	if v == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(v), []byte(answer)) == 1
+}
+
+func NewStore(logger *zap.Logger, db *badger.DB, ttl time.Duration) *Store {
+	return &Store{
+		logger: logger,
+		db:     db,
+		ttl:    ttl,
+	}
+}
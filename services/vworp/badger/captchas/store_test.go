@@ -0,0 +1,61 @@
+package captchas_test
+
+import (
+	badger "github.com/dgraph-io/badger/v2"
+	"github.com/onionltd/mono/services/vworp/badger/captchas"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store := captchas.NewStore(zap.NewNop(), db, time.Minute)
+	store.Set("abc", "1234")
+
+	assert.Equal(t, "1234", store.Get("abc", false))
+	assert.Equal(t, "", store.Get("made-up", false))
+
+	// Captcha is removed once it's cleared.
+	assert.Equal(t, "1234", store.Get("abc", true))
+	assert.Equal(t, "", store.Get("abc", false))
+	assert.Equal(t, "", store.Get("abc", true))
+
+	store.Set("abc", "1234")
+	assert.False(t, store.Verify("abc", "4321", false))
+	assert.True(t, store.Verify("abc", "1234", true))
+	// Captcha can be verified only once.
+	assert.False(t, store.Verify("abc", "1234", true))
+	assert.False(t, store.Verify("made-up", "", true))
+}
+
+func TestStoreExpires(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store := captchas.NewStore(zap.NewNop(), db, -time.Minute)
+	store.Set("abc", "1234")
+
+	assert.Equal(t, "", store.Get("abc", false))
+	assert.False(t, store.Verify("abc", "1234", true))
+}
@@ -0,0 +1,137 @@
+package links
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dgraph-io/badger/v2"
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
+	"strings"
+	"time"
+)
+
+// serviceIndexEntry maps a service to one of its links. Entries are kept consistent with links
+// by writing them in the same transaction.
+type serviceIndexEntry struct {
+	serviceID   string
+	fingerprint string
+	expires     time.Time
+}
+
+// Methods to fulfill badger interface.
+func (e serviceIndexEntry) Key() badgerutil.Key {
+	return NewServiceIndexKey(e.serviceID, e.fingerprint)
+}
+
+func (e *serviceIndexEntry) SetKey(k badgerutil.Key) {
+	tokens := strings.SplitN(string(k), ".", 3)
+	e.serviceID = tokens[1]
+	e.fingerprint = tokens[2]
+}
+
+func (e serviceIndexEntry) Value() ([]byte, error) {
+	return nil, nil
+}
+
+func (e *serviceIndexEntry) SetValue(v []byte) error {
+	return nil
+}
+
+func (e serviceIndexEntry) Meta() byte { return 0 }
+
+func (e serviceIndexEntry) SetMeta(m byte) {}
+
+func (e serviceIndexEntry) Expires() time.Time { return e.expires }
+
+func (e *serviceIndexEntry) SetExpires(t time.Time) { e.expires = t }
+
+func (e serviceIndexEntry) Error() string { return "" }
+
+const serviceIndexKeyPrefix = "links_by_service"
+
+func NewServiceIndexKey(serviceID, fingerprint string) badgerutil.Key {
+	return badgerutil.Key(fmt.Sprintf("%s.%s", newServiceIndexPrefix(serviceID), fingerprint))
+}
+
+func newServiceIndexPrefix(serviceID string) string {
+	return fmt.Sprintf("%s.%s", serviceIndexKeyPrefix, serviceID)
+}
+
+func newServiceIndexEntry(l *Link) *serviceIndexEntry {
+	return &serviceIndexEntry{
+		serviceID:   l.serviceID,
+		fingerprint: l.fingerprint,
+		expires:     l.Expires(),
+	}
+}
+
+// IndexTxn adds the link to the index of its service.
+func IndexTxn(txn *badger.Txn, l *Link) error {
+	return badgerutil.StoreTxn(txn, newServiceIndexEntry(l))
+}
+
+// ListByServiceTxn returns up to limit links of the service, ordered by fingerprint. Listing starts
+// after the given fingerprint, which makes it possible to iterate the links page by page.
+func ListByServiceTxn(txn *badger.Txn, serviceID, after string, limit int) ([]*Link, error) {
+	prefix := []byte(newServiceIndexPrefix(serviceID) + ".")
+	start := prefix
+	if after != "" {
+		// Skip the fingerprint itself.
+		start = append(NewServiceIndexKey(serviceID, after), 0)
+	}
+
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	result := []*Link{}
+	for it.Seek(start); it.ValidForPrefix(prefix) && len(result) < limit; it.Next() {
+		fingerprint := strings.TrimPrefix(string(it.Item().Key()), string(prefix))
+		l := &Link{}
+		if err := badgerutil.LoadTxn(txn, NewKey(fingerprint), l); err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		result = append(result, l)
+	}
+	return result, nil
+}
+
+// indexBatchSize limits how many index entries are written in a single transaction.
+const indexBatchSize = 1000
+
+// IndexAll adds all links to indexes of their services. It's safe to run it again.
+func IndexAll(db *badger.DB) error {
+	all := []*Link{}
+	err := db.View(func(txn *badger.Txn) error {
+		return ForEachTxn(txn, func(l *Link) error {
+			all = append(all, l)
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	for len(all) > 0 {
+		n := indexBatchSize
+		if n > len(all) {
+			n = len(all)
+		}
+		err := db.Update(func(txn *badger.Txn) error {
+			for _, l := range all[:n] {
+				if err := IndexTxn(txn, l); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		all = all[n:]
+	}
+	return nil
+}
@@ -1,7 +1,10 @@
 package links
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/onionltd/mono/pkg/utils/badger"
@@ -11,21 +14,36 @@ import (
 
 const (
 	FingerprintLength = 5
+	// FingerprintMaxLength specifies the length the fingerprint can be extended to when it collides
+	// with a fingerprint of another link.
+	FingerprintMaxLength = sha256.Size
 	// FingerprintLimitBytes specifies how many bytes of the value are used to calculate the fingerprint.
 	// It's here to prevent DoS where an attacker sends large URLs that needs to be hashed.
 	FingerprintLimitBytes = 128
+	// ExpiredRetention specifies how long an expired link is kept in the database. Within this period
+	// the link can still be loaded and reported as expired, rather than as a link that never existed.
+	ExpiredRetention = 30 * 24 * time.Hour
+	// TokenLength specifies number of random bytes in a management token.
+	TokenLength = 16
 )
 
 // linkBare is a structure that is actually stored in badger.
 type linkBare struct {
 	ServiceID string `json:"service_id"`
 	Path      string `json:"path"`
+	TokenHash string `json:"token_hash,omitempty"`
+	Slug      string `json:"slug,omitempty"`
+	Shared    bool   `json:"shared,omitempty"`
 }
 
 type Link struct {
 	fingerprint string
 	serviceID   string
 	path        string
+	expiresAt   time.Time
+	tokenHash   string
+	slug        string
+	shared      bool
 }
 
 func (l Link) Fingerprint() string {
@@ -40,6 +58,59 @@ func (l Link) Path() string {
 	return l.path
 }
 
+func (l *Link) SetPath(path string) {
+	l.path = path
+}
+
+// Slug returns a human-readable name of the link, if it has one.
+func (l Link) Slug() string {
+	return l.slug
+}
+
+func (l *Link) SetSlug(slug string) {
+	l.slug = slug
+}
+
+// Shared returns true if the link was given to someone else than who created it, because they
+// submitted the same URL.
+func (l Link) Shared() bool {
+	return l.shared
+}
+
+// HasToken returns true if the link can be managed with a management token.
+func (l Link) HasToken() bool {
+	return l.tokenHash != ""
+}
+
+// SetToken sets a management token of the link. Only a hash of the token is stored.
+func (l *Link) SetToken(token string) {
+	l.tokenHash = hashToken(token)
+}
+
+func (l Link) VerifyToken(token string) bool {
+	if !l.HasToken() || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(l.tokenHash), []byte(hashToken(token))) == 1
+}
+
+// ExpiresAt returns time when the link expires. Zero time means the link never expires.
+func (l Link) ExpiresAt() time.Time {
+	return l.expiresAt
+}
+
+func (l Link) Expired() bool {
+	return !l.expiresAt.IsZero() && !time.Now().Before(l.expiresAt)
+}
+
+func (l *Link) SetExpiresAt(t time.Time) {
+	if t.IsZero() {
+		l.expiresAt = time.Time{}
+		return
+	}
+	l.expiresAt = time.Unix(t.Unix(), 0)
+}
+
 //
 // Methods to fulfill badger interface.
 //
@@ -56,6 +127,9 @@ func (l Link) Value() ([]byte, error) {
 	return json.Marshal(linkBare{
 		ServiceID: l.serviceID,
 		Path:      l.path,
+		TokenHash: l.tokenHash,
+		Slug:      l.slug,
+		Shared:    l.shared,
 	})
 }
 
@@ -66,6 +140,9 @@ func (l *Link) SetValue(v []byte) error {
 	}
 	l.serviceID = bare.ServiceID
 	l.path = bare.Path
+	l.tokenHash = bare.TokenHash
+	l.slug = bare.Slug
+	l.shared = bare.Shared
 	return nil
 }
 
@@ -73,9 +150,21 @@ func (l Link) Meta() byte { return 0 }
 
 func (l Link) SetMeta(m byte) {}
 
-func (l Link) Expires() time.Time { return time.Unix(0, 0) }
+// Expires returns time when the key is removed from the database.
+func (l Link) Expires() time.Time {
+	if l.expiresAt.IsZero() {
+		return time.Time{}
+	}
+	return l.expiresAt.Add(ExpiredRetention)
+}
 
-func (l *Link) SetExpires(t time.Time) {}
+func (l *Link) SetExpires(t time.Time) {
+	if t.IsZero() {
+		l.expiresAt = time.Time{}
+		return
+	}
+	l.SetExpiresAt(t.Add(-ExpiredRetention))
+}
 
 func (l Link) Error() string { return "" }
 
@@ -86,20 +175,37 @@ func NewKey(fingerprint string) badger.Key {
 }
 
 func NewLink(serviceID, path string) (*Link, error) {
-	fingerprint := func(serviceID, url string) string {
-		// TODO: optimize this part!
-		// 	https://golang.org/pkg/strings/#Builder
-		// TODO: trim `b` to constant length
-		b := []byte(fmt.Sprintf("%s/%s", serviceID, url))
-		if len(b) > FingerprintLimitBytes {
-			b = b[:FingerprintLimitBytes]
-		}
-		sum := sha256.Sum256(b)
-		return fmt.Sprintf("%x", sum[:FingerprintLength])
-	}
 	return &Link{
-		fingerprint: fingerprint(serviceID, path),
+		fingerprint: fingerprint(serviceID, path, FingerprintLength),
 		serviceID:   serviceID,
 		path:        path,
 	}, nil
 }
+
+// NewToken returns a random management token.
+func NewToken() (string, error) {
+	b := make([]byte, TokenLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// fingerprint returns first length bytes of the link's hash, hex encoded. Extended fingerprints
+// are calculated from the whole value, otherwise links sharing the first FingerprintLimitBytes
+// would collide regardless of the length.
+func fingerprint(serviceID, path string, length int) string {
+	// TODO: optimize this part!
+	// 	https://golang.org/pkg/strings/#Builder
+	b := []byte(fmt.Sprintf("%s/%s", serviceID, path))
+	if len(b) > FingerprintLimitBytes && length <= FingerprintLength {
+		b = b[:FingerprintLimitBytes]
+	}
+	sum := sha256.Sum256(b)
+	return fmt.Sprintf("%x", sum[:length])
+}
@@ -6,7 +6,9 @@ import (
 	"github.com/onionltd/mono/services/vworp/badger/links"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestStore(t *testing.T) {
@@ -41,6 +43,438 @@ func TestStore(t *testing.T) {
 	assert.Equal(t, readLink, link)
 }
 
+func TestStoreExpires(t *testing.T) {
+	const (
+		serviceID = "example"
+		url       = "/article/why-birds-flap-their-wings"
+	)
+	link, err := links.NewLink(serviceID, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	link.SetExpiresAt(time.Now().Add(24 * time.Hour))
+
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := badgerutil.Store(db, link); err != nil {
+		t.Fatal(err)
+	}
+
+	readLink := &links.Link{}
+	if err := badgerutil.Load(db, link.Key(), readLink); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, link.ExpiresAt(), readLink.ExpiresAt())
+	assert.False(t, readLink.Expired())
+
+	// Expired links are kept in the database for a while.
+	link.SetExpiresAt(time.Now().Add(-time.Hour))
+	if err := badgerutil.Store(db, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := badgerutil.Load(db, link.Key(), readLink); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, readLink.Expired())
+}
+
+func TestStoreCollision(t *testing.T) {
+	const (
+		serviceID = "example"
+		url       = "/article/why-birds-flap-their-wings"
+	)
+	link, err := links.NewLink(serviceID, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint := link.Fingerprint()
+
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Occupy the link's fingerprint with a link pointing elsewhere.
+	other, err := links.NewLink("other", "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherValue, err := other.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Update(func(txn *badger.Txn) error {
+		return txn.Set(links.NewKey(fingerprint), otherValue)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := links.Store(db, link); err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, fingerprint, link.Fingerprint())
+	assert.Len(t, link.Fingerprint(), 2*(links.FingerprintLength+1))
+	assert.True(t, strings.HasPrefix(link.Fingerprint(), fingerprint))
+
+	// The colliding link must remain untouched.
+	readOther := &links.Link{}
+	if err := badgerutil.Load(db, links.NewKey(fingerprint), readOther); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "other", readOther.ServiceID())
+
+	readLink := &links.Link{}
+	if err := badgerutil.Load(db, link.Key(), readLink); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, readLink, link)
+
+	// Storing the same link again must yield the same fingerprint.
+	sameLink, err := links.NewLink(serviceID, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := links.Store(db, sameLink); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, link.Fingerprint(), sameLink.Fingerprint())
+
+	// Once the colliding link is gone, the link must still be found under its longer fingerprint.
+	token, err := links.NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	link.SetToken(token)
+	if err := links.Update(db, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(links.NewKey(fingerprint))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sameLink, err = links.NewLink(serviceID, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := links.Store(db, sameLink); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, link.Fingerprint(), sameLink.Fingerprint())
+	assert.True(t, sameLink.VerifyToken(token))
+
+	readOther = &links.Link{}
+	err = badgerutil.Load(db, links.NewKey(fingerprint), readOther)
+	assert.Equal(t, badger.ErrKeyNotFound, err)
+}
+
+func TestStoreKeepsLifetime(t *testing.T) {
+	const (
+		serviceID = "example"
+		url       = "/article/why-birds-flap-their-wings"
+	)
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	link, err := links.NewLink(serviceID, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := links.Store(db, link); err != nil {
+		t.Fatal(err)
+	}
+
+	shortLived, err := links.NewLink(serviceID, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shortLived.SetExpiresAt(time.Now().Add(time.Hour))
+	if err := links.Store(db, shortLived); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, link.Fingerprint(), shortLived.Fingerprint())
+	assert.True(t, shortLived.ExpiresAt().IsZero())
+}
+
+func TestStoreKeepsToken(t *testing.T) {
+	const (
+		serviceID = "example"
+		url       = "/article/why-birds-flap-their-wings"
+	)
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	newLinkWithToken := func() (*links.Link, string) {
+		link, err := links.NewLink(serviceID, url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := links.NewToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		link.SetToken(token)
+		if err := links.Store(db, link); err != nil {
+			t.Fatal(err)
+		}
+		return link, token
+	}
+
+	link, token := newLinkWithToken()
+	assert.True(t, link.VerifyToken(token))
+	assert.False(t, link.Shared())
+
+	// The second creator must not take over the link.
+	sameLink, sameToken := newLinkWithToken()
+	assert.False(t, sameLink.VerifyToken(sameToken))
+	assert.True(t, sameLink.VerifyToken(token))
+	assert.True(t, sameLink.Shared())
+
+	readLink := &links.Link{}
+	if err := badgerutil.Load(db, link.Key(), readLink); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, readLink.VerifyToken(token))
+	assert.False(t, readLink.VerifyToken(""))
+	assert.True(t, readLink.Shared())
+}
+
+func TestMoveTxn(t *testing.T) {
+	const serviceID = "example"
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	newLink := func(path string) *links.Link {
+		link, err := links.NewLink(serviceID, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return link
+	}
+	move := func(link *links.Link, path string) (moved *links.Link, err error) {
+		err = db.Update(func(txn *badger.Txn) error {
+			moved, err = links.MoveTxn(txn, link, path)
+			return err
+		})
+		return
+	}
+
+	token, err := links.NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	link := newLink("/article/why-birds-flap-their-wings")
+	link.SetToken(token)
+	link.SetSlug("birds")
+	if err := links.Store(db, link); err != nil {
+		t.Fatal(err)
+	}
+	other := newLink("/article/why-fish-swim")
+	if err := links.Store(db, other); err != nil {
+		t.Fatal(err)
+	}
+
+	moved, err := move(link, "/article/why-birds-sing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, link.Fingerprint(), moved.Fingerprint())
+	assert.Equal(t, newLink("/article/why-birds-sing").Fingerprint(), moved.Fingerprint())
+	assert.True(t, moved.VerifyToken(token))
+	assert.Equal(t, "birds", moved.Slug())
+
+	// The old record must be gone, it no longer matches its fingerprint.
+	readLink := &links.Link{}
+	err = badgerutil.Load(db, link.Key(), readLink)
+	assert.Equal(t, badger.ErrKeyNotFound, err)
+
+	readLink = &links.Link{}
+	if err := badgerutil.Load(db, moved.Key(), readLink); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "/article/why-birds-sing", readLink.Path())
+	assert.True(t, readLink.VerifyToken(token))
+
+	err = db.View(func(txn *badger.Txn) error {
+		result, err := links.ListByServiceTxn(txn, serviceID, "", 10)
+		assert.Len(t, result, 2)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A link must not be moved over another link.
+	_, err = move(moved, other.Path())
+	assert.Equal(t, links.ErrExists, err)
+
+	// A shared link is kept in place for the others, with its slug.
+	shared := newLink(moved.Path())
+	if err := links.Store(db, shared); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, shared.Shared())
+	copied, err := move(shared, "/article/why-birds-migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, copied.Shared())
+	assert.True(t, copied.VerifyToken(token))
+	assert.Empty(t, copied.Slug())
+
+	readLink = &links.Link{}
+	if err := badgerutil.Load(db, shared.Key(), readLink); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "/article/why-birds-sing", readLink.Path())
+	assert.Equal(t, "birds", readLink.Slug())
+	assert.True(t, readLink.Shared())
+}
+
+func TestListByService(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store := func(serviceID, url string) *links.Link {
+		link, err := links.NewLink(serviceID, url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := links.Store(db, link); err != nil {
+			t.Fatal(err)
+		}
+		return link
+	}
+	list := func(after string, limit int) []*links.Link {
+		var result []*links.Link
+		if err := db.View(func(txn *badger.Txn) error {
+			result, err = links.ListByServiceTxn(txn, "example", after, limit)
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	stored := []*links.Link{
+		store("example", "/1"),
+		store("example", "/2"),
+		store("example", "/3"),
+	}
+	store("other", "/1")
+
+	firstPage := list("", 2)
+	assert.Len(t, firstPage, 2)
+	secondPage := list(firstPage[1].Fingerprint(), 2)
+	assert.Len(t, secondPage, 1)
+	for _, link := range append(firstPage, secondPage...) {
+		assert.Equal(t, "example", link.ServiceID())
+	}
+
+	// Deleted links are removed from the index.
+	if err := links.Delete(db, stored[0]); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, list("", 10), 2)
+}
+
+func TestIndexAllMigration(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Links as they were stored before the index was introduced.
+	fixtures := map[string]string{
+		"links.0a1b2c3d4e": `{"service_id":"example","path":"/1"}`,
+		"links.5f6a7b8c9d": `{"service_id":"example","path":"/2","slug":"two"}`,
+		"links.0e1f2a3b4c": `{"service_id":"other","path":"/"}`,
+	}
+	if err := db.Update(func(txn *badger.Txn) error {
+		for k, v := range fixtures {
+			if err := txn.Set([]byte(k), []byte(v)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	migrations := []badgerutil.Migration{
+		{Version: 1, Migrate: links.IndexAll},
+	}
+	if _, err := badgerutil.Migrate(db, migrations); err != nil {
+		t.Fatal(err)
+	}
+
+	var result []*links.Link
+	if err := db.View(func(txn *badger.Txn) error {
+		result, err = links.ListByServiceTxn(txn, "example", "", 10)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, result, 2) {
+		assert.Equal(t, "0a1b2c3d4e", result[0].Fingerprint())
+		assert.Equal(t, "/1", result[0].Path())
+		assert.Equal(t, "two", result[1].Slug())
+	}
+}
+
 func TestNewLink(t *testing.T) {
 	const (
 		serviceID = "example"
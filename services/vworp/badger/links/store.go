@@ -0,0 +1,162 @@
+package links
+
+import (
+	"errors"
+	"github.com/dgraph-io/badger/v2"
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
+)
+
+var (
+	ErrFingerprintCollision = errors.New("unable to find a unique fingerprint")
+	ErrExists               = errors.New("link already exists")
+)
+
+// Store writes the link to the database. If the link's fingerprint is already taken by a link
+// pointing elsewhere, the fingerprint is extended until it's unique. The extension is deterministic,
+// storing the same link again yields the same fingerprint. If the same link is already stored,
+// its lifetime is never shortened, its management token and slug are kept and it becomes shared.
+func Store(db *badger.DB, l *Link) error {
+	return db.Update(func(txn *badger.Txn) error {
+		return StoreTxn(txn, l)
+	})
+}
+
+// StoreTxn is like Store but writes the link within an existing transaction.
+func StoreTxn(txn *badger.Txn, l *Link) error {
+	existing, err := ResolveTxn(txn, l)
+	if err != nil {
+		return err
+	}
+	if existing != nil && !existing.Expired() {
+		if existing.outlives(l) {
+			l.expiresAt = existing.expiresAt
+		}
+		l.tokenHash = existing.tokenHash
+		l.slug = existing.slug
+		l.shared = true
+	}
+	return storeTxn(txn, l)
+}
+
+// ResolveTxn sets the fingerprint the link would be stored under. If the same link is already
+// stored under any length of the fingerprint, the stored link is returned. Otherwise, the link
+// is given the shortest fingerprint that's not taken and nil is returned. All lengths have to be
+// checked, a shorter fingerprint may have been freed after the link was stored under a longer one.
+func ResolveTxn(txn *badger.Txn, l *Link) (*Link, error) {
+	free := ""
+	for length := FingerprintLength; length <= FingerprintMaxLength; length++ {
+		fpr := fingerprint(l.serviceID, l.path, length)
+
+		existing := &Link{}
+		if err := badgerutil.LoadTxn(txn, NewKey(fpr), existing); err != nil {
+			if !errors.Is(err, badger.ErrKeyNotFound) {
+				return nil, err
+			}
+			if free == "" {
+				free = fpr
+			}
+			continue
+		}
+
+		if l.sameTarget(existing) {
+			l.fingerprint = fpr
+			return existing, nil
+		}
+	}
+	if free == "" {
+		return nil, ErrFingerprintCollision
+	}
+	l.fingerprint = free
+	return nil, nil
+}
+
+// Update writes the link to the database under its current fingerprint.
+func Update(db *badger.DB, l *Link) error {
+	return db.Update(func(txn *badger.Txn) error {
+		return storeTxn(txn, l)
+	})
+}
+
+// MoveTxn changes the path of the link. Fingerprints are derived from the path, so the link is stored
+// under a new fingerprint and the old record is removed. A shared link is kept in place with its slug
+// for the others, only its unshared copy without the slug is moved. ErrExists is returned if a link
+// to the path already exists. The moved link is returned.
+func MoveTxn(txn *badger.Txn, l *Link, path string) (*Link, error) {
+	moved := *l
+	moved.path = path
+	if l.shared {
+		moved.shared = false
+		moved.slug = ""
+	}
+	existing, err := ResolveTxn(txn, &moved)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && !existing.Expired() {
+		return nil, ErrExists
+	}
+	if !l.shared {
+		if err := DeleteTxn(txn, l); err != nil {
+			return nil, err
+		}
+	}
+	if err := storeTxn(txn, &moved); err != nil {
+		return nil, err
+	}
+	return &moved, nil
+}
+
+func Delete(db *badger.DB, l *Link) error {
+	return db.Update(func(txn *badger.Txn) error {
+		return DeleteTxn(txn, l)
+	})
+}
+
+// DeleteTxn is like Delete but removes the link within an existing transaction.
+func DeleteTxn(txn *badger.Txn, l *Link) error {
+	if err := txn.Delete(NewServiceIndexKey(l.serviceID, l.fingerprint)); err != nil {
+		return err
+	}
+	return txn.Delete(l.Key())
+}
+
+// storeTxn writes the link together with its index entry.
+func storeTxn(txn *badger.Txn, l *Link) error {
+	if err := badgerutil.StoreTxn(txn, l); err != nil {
+		return err
+	}
+	return IndexTxn(txn, l)
+}
+
+// ForEachTxn calls fn for every link in the database, expired links included. Iteration stops
+// if fn returns an error.
+func ForEachTxn(txn *badger.Txn, fn func(l *Link) error) error {
+	prefix := []byte(keyPrefix + ".")
+	it := txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		l := &Link{}
+		if err := badgerutil.LoadTxn(txn, it.Item().KeyCopy(nil), l); err != nil {
+			return err
+		}
+		if err := fn(l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l Link) sameTarget(other *Link) bool {
+	return l.serviceID == other.serviceID && l.path == other.path
+}
+
+// outlives returns true if the link expires later than the other link.
+func (l Link) outlives(other *Link) bool {
+	if l.expiresAt.IsZero() {
+		return true
+	}
+	if other.expiresAt.IsZero() {
+		return false
+	}
+	return l.expiresAt.After(other.expiresAt)
+}
@@ -0,0 +1,142 @@
+package moderation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/onionltd/mono/pkg/utils/badger"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Kind tells what a block is matched against.
+type Kind string
+
+const (
+	KindFingerprint Kind = "fingerprint"
+	KindService     Kind = "service"
+	KindPath        Kind = "path"
+)
+
+var (
+	ErrInvalidKind    = errors.New("invalid kind of block")
+	ErrInvalidPattern = errors.New("invalid pattern")
+)
+
+type blockBare struct {
+	Kind      Kind   `json:"kind"`
+	Pattern   string `json:"pattern"`
+	Reason    string `json:"reason,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// Block disables links to a service, links with a fingerprint or links with a path matching a regular expression.
+type Block struct {
+	id        string
+	kind      Kind
+	pattern   string
+	reason    string
+	createdAt time.Time
+}
+
+// ID is derived from the kind and the pattern, blocking the same thing twice yields the same ID.
+func (b Block) ID() string {
+	return b.id
+}
+
+func (b Block) Kind() Kind {
+	return b.kind
+}
+
+// Pattern returns a fingerprint, a service ID or a regular expression, depending on the kind.
+func (b Block) Pattern() string {
+	return b.pattern
+}
+
+func (b Block) Reason() string {
+	return b.reason
+}
+
+func (b Block) CreatedAt() time.Time {
+	return b.createdAt
+}
+
+// Methods to fulfill badger interface.
+func (b Block) Key() badger.Key {
+	return NewKey(b.id)
+}
+
+func (b *Block) SetKey(k badger.Key) {
+	tokens := strings.SplitN(string(k), ".", 2)
+	b.id = tokens[1]
+}
+
+func (b Block) Value() ([]byte, error) {
+	return json.Marshal(blockBare{
+		Kind:      b.kind,
+		Pattern:   b.pattern,
+		Reason:    b.reason,
+		CreatedAt: b.createdAt.Unix(),
+	})
+}
+
+func (b *Block) SetValue(v []byte) error {
+	bare := blockBare{}
+	if err := json.Unmarshal(v, &bare); err != nil {
+		return err
+	}
+	b.kind = bare.Kind
+	b.pattern = bare.Pattern
+	b.reason = bare.Reason
+	b.createdAt = time.Unix(bare.CreatedAt, 0)
+	return nil
+}
+
+func (b Block) Meta() byte { return 0 }
+
+func (b Block) SetMeta(m byte) {}
+
+func (b Block) Expires() time.Time { return time.Time{} }
+
+func (b Block) SetExpires(t time.Time) {}
+
+func (b Block) Error() string { return "" }
+
+const keyPrefix = "blocks"
+
+func NewKey(id string) badger.Key {
+	return badger.Key(fmt.Sprintf("%s.%s", keyPrefix, id))
+}
+
+func blockID(kind Kind, pattern string) string {
+	sum := sha256.Sum256([]byte(string(kind) + "\x00" + pattern))
+	return hex.EncodeToString(sum[:8])
+}
+
+// NewBlock validates the pattern and returns a new block. Patterns of path blocks must be
+// valid regular expressions.
+func NewBlock(kind Kind, pattern, reason string) (*Block, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil, ErrInvalidPattern
+	}
+	switch kind {
+	case KindFingerprint, KindService:
+	case KindPath:
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, ErrInvalidPattern
+		}
+	default:
+		return nil, ErrInvalidKind
+	}
+	return &Block{
+		id:        blockID(kind, pattern),
+		kind:      kind,
+		pattern:   pattern,
+		reason:    strings.TrimSpace(reason),
+		createdAt: time.Now(),
+	}, nil
+}
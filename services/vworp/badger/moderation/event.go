@@ -0,0 +1,123 @@
+package moderation
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/onionltd/mono/pkg/utils/badger"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Action is a moderation action recorded in the audit trail.
+type Action string
+
+const (
+	ActionBlock   Action = "block"
+	ActionUnblock Action = "unblock"
+)
+
+type eventBare struct {
+	Action  Action `json:"action"`
+	BlockID string `json:"block_id"`
+	Kind    Kind   `json:"kind"`
+	Pattern string `json:"pattern"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Event is an entry of the audit trail. Events are never removed from the database.
+type Event struct {
+	createdAt time.Time
+	action    Action
+	blockID   string
+	kind      Kind
+	pattern   string
+	reason    string
+}
+
+func (e Event) CreatedAt() time.Time {
+	return e.createdAt
+}
+
+func (e Event) Action() Action {
+	return e.action
+}
+
+func (e Event) BlockID() string {
+	return e.blockID
+}
+
+func (e Event) Kind() Kind {
+	return e.kind
+}
+
+func (e Event) Pattern() string {
+	return e.pattern
+}
+
+func (e Event) Reason() string {
+	return e.reason
+}
+
+// Methods to fulfill badger interface.
+func (e Event) Key() badger.Key {
+	return NewEventKey(e.createdAt, e.blockID)
+}
+
+func (e *Event) SetKey(k badger.Key) {
+	tokens := strings.SplitN(string(k), ".", 3)
+	nsec, _ := strconv.ParseInt(tokens[1], 10, 64)
+	e.createdAt = time.Unix(0, nsec)
+	e.blockID = tokens[2]
+}
+
+func (e Event) Value() ([]byte, error) {
+	return json.Marshal(eventBare{
+		Action:  e.action,
+		BlockID: e.blockID,
+		Kind:    e.kind,
+		Pattern: e.pattern,
+		Reason:  e.reason,
+	})
+}
+
+func (e *Event) SetValue(v []byte) error {
+	bare := eventBare{}
+	if err := json.Unmarshal(v, &bare); err != nil {
+		return err
+	}
+	e.action = bare.Action
+	e.kind = bare.Kind
+	e.pattern = bare.Pattern
+	e.reason = bare.Reason
+	return nil
+}
+
+func (e Event) Meta() byte { return 0 }
+
+func (e Event) SetMeta(m byte) {}
+
+func (e Event) Expires() time.Time { return time.Time{} }
+
+func (e Event) SetExpires(t time.Time) {}
+
+func (e Event) Error() string { return "" }
+
+const eventKeyPrefix = "moderation_log"
+
+// NewEventKey returns a key of an event. Timestamps are zero-padded, so that keys sort chronologically.
+func NewEventKey(createdAt time.Time, blockID string) badger.Key {
+	return badger.Key(fmt.Sprintf("%s.%020d.%s", eventKeyPrefix, createdAt.UnixNano(), blockID))
+}
+
+// NewEvent records the action taken on the block, reason explains the action.
+func NewEvent(action Action, b *Block, reason string) *Event {
+	return &Event{
+		createdAt: time.Now(),
+		action:    action,
+		blockID:   b.id,
+		kind:      b.kind,
+		pattern:   b.pattern,
+		reason:    strings.TrimSpace(reason),
+	}
+}
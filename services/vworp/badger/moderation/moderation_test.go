@@ -0,0 +1,76 @@
+package moderation_test
+
+import (
+	badger "github.com/dgraph-io/badger/v2"
+	"github.com/onionltd/mono/services/vworp/badger/moderation"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
+func TestBlocks(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = moderation.NewBlock(moderation.KindPath, "(", "")
+	assert.Equal(t, moderation.ErrInvalidPattern, err)
+	_, err = moderation.NewBlock("link", "abcdef", "")
+	assert.Equal(t, moderation.ErrInvalidKind, err)
+
+	service, err := moderation.NewBlock(moderation.KindService, "evil", "phishing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, err := moderation.NewBlock(moderation.KindPath, "^/admin", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []*moderation.Block{service, path} {
+		if err := moderation.Add(db, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	blocks, err := moderation.ListBlocks(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, blocks, 2)
+
+	removed, err := moderation.Remove(db, service.ID(), "appeal accepted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "evil", removed.Pattern())
+	_, err = moderation.Remove(db, service.ID(), "")
+	assert.Equal(t, badger.ErrKeyNotFound, err)
+
+	blocks, err = moderation.ListBlocks(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, blocks, 1) {
+		assert.Equal(t, path.ID(), blocks[0].ID())
+		assert.Equal(t, moderation.KindPath, blocks[0].Kind())
+	}
+
+	// The audit trail is listed the newest first.
+	events, err := moderation.ListEvents(db, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, moderation.ActionUnblock, events[0].Action())
+		assert.Equal(t, "appeal accepted", events[0].Reason())
+		assert.Equal(t, moderation.ActionBlock, events[1].Action())
+		assert.Equal(t, path.ID(), events[1].BlockID())
+	}
+}
@@ -0,0 +1,85 @@
+package moderation
+
+import (
+	"github.com/dgraph-io/badger/v2"
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
+)
+
+// Add stores the block and records the action in the audit trail in the same transaction.
+func Add(db *badger.DB, b *Block) error {
+	return db.Update(func(txn *badger.Txn) error {
+		if err := badgerutil.StoreTxn(txn, b); err != nil {
+			return err
+		}
+		return badgerutil.StoreTxn(txn, NewEvent(ActionBlock, b, b.reason))
+	})
+}
+
+// Remove deletes the block with the given ID and records the action in the audit trail.
+// If there's no such block, badger.ErrKeyNotFound is returned.
+func Remove(db *badger.DB, id, reason string) (*Block, error) {
+	b := &Block{}
+	err := db.Update(func(txn *badger.Txn) error {
+		if err := badgerutil.LoadTxn(txn, NewKey(id), b); err != nil {
+			return err
+		}
+		if err := txn.Delete(b.Key()); err != nil {
+			return err
+		}
+		return badgerutil.StoreTxn(txn, NewEvent(ActionUnblock, b, reason))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ListBlocks returns all blocks, ordered by ID.
+func ListBlocks(db *badger.DB) ([]*Block, error) {
+	prefix := []byte(keyPrefix + ".")
+	result := []*Block{}
+	err := db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			b := &Block{}
+			if err := badgerutil.LoadTxn(txn, it.Item().KeyCopy(nil), b); err != nil {
+				return err
+			}
+			result = append(result, b)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ListEvents returns up to limit most recent events of the audit trail, the newest first.
+func ListEvents(db *badger.DB, limit int) ([]*Event, error) {
+	prefix := []byte(eventKeyPrefix + ".")
+	result := []*Event{}
+	err := db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Reverse = true
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		// Reverse iteration has to start after the last key with the prefix, '/' sorts right after '.'.
+		start := []byte(eventKeyPrefix + "/")
+		for it.Seek(start); it.ValidForPrefix(prefix) && len(result) < limit; it.Next() {
+			e := &Event{}
+			if err := badgerutil.LoadTxn(txn, it.Item().KeyCopy(nil), e); err != nil {
+				return err
+			}
+			result = append(result, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,105 @@
+package ratelimits
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/onionltd/mono/pkg/utils/badger"
+	"strings"
+	"time"
+)
+
+type bucketBare struct {
+	Tokens    float64 `json:"tokens"`
+	UpdatedAt int64   `json:"updated_at"`
+}
+
+// Bucket is a token bucket, which holds up to limit tokens and is refilled at the rate of limit tokens per period.
+type Bucket struct {
+	name      string
+	tokens    float64
+	updatedAt time.Time
+	expires   time.Time
+}
+
+func (b Bucket) Name() string {
+	return b.name
+}
+
+func (b Bucket) Tokens() float64 {
+	return b.tokens
+}
+
+// Take refills the bucket and takes a token from it. False is returned if the bucket is empty.
+func (b *Bucket) Take(limit int, period time.Duration, now time.Time) bool {
+	if elapsed := now.Sub(b.updatedAt); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * float64(limit) / period.Seconds()
+	}
+	if b.tokens > float64(limit) {
+		b.tokens = float64(limit)
+	}
+	b.updatedAt = now
+	// A full bucket is no different from a missing one.
+	b.expires = now.Add(period)
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Methods to fulfill badger interface.
+func (b Bucket) Key() badger.Key {
+	return NewBucketKey(b.name)
+}
+
+func (b *Bucket) SetKey(k badger.Key) {
+	tokens := strings.SplitN(string(k), ".", 3)
+	b.name = tokens[2]
+}
+
+func (b Bucket) Value() ([]byte, error) {
+	return json.Marshal(bucketBare{
+		Tokens:    b.tokens,
+		UpdatedAt: b.updatedAt.UnixNano(),
+	})
+}
+
+func (b *Bucket) SetValue(v []byte) error {
+	bare := bucketBare{}
+	if err := json.Unmarshal(v, &bare); err != nil {
+		return err
+	}
+	b.tokens = bare.Tokens
+	b.updatedAt = time.Unix(0, bare.UpdatedAt)
+	return nil
+}
+
+func (b Bucket) Meta() byte { return 0 }
+
+func (b Bucket) SetMeta(m byte) {}
+
+func (b Bucket) Expires() time.Time { return b.expires }
+
+func (b *Bucket) SetExpires(t time.Time) { b.expires = t }
+
+func (b Bucket) Error() string { return "" }
+
+const (
+	keyPrefix        = "ratelimits"
+	bucketKeyPrefix  = keyPrefix + ".buckets"
+	counterKeyPrefix = keyPrefix + ".counters"
+)
+
+func NewBucketKey(name string) badger.Key {
+	return badger.Key(fmt.Sprintf("%s.%s", bucketKeyPrefix, name))
+}
+
+// NewBucket returns a full bucket.
+func NewBucket(name string, limit int, now time.Time) *Bucket {
+	return &Bucket{
+		name:      name,
+		tokens:    float64(limit),
+		updatedAt: now,
+	}
+}
@@ -0,0 +1,80 @@
+package ratelimits
+
+import (
+	"fmt"
+	"github.com/onionltd/mono/pkg/utils/badger"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Counter counts events within a fixed time window. The counter is removed from the database
+// when the window ends.
+type Counter struct {
+	name    string
+	count   uint64
+	expires time.Time
+}
+
+func (c Counter) Name() string {
+	return c.name
+}
+
+func (c Counter) Count() uint64 {
+	return c.count
+}
+
+// Increment increments the counter, unless it has reached the quota. False is returned if the quota is exhausted.
+func (c *Counter) Increment(quota int) bool {
+	if c.count >= uint64(quota) {
+		return false
+	}
+	c.count++
+	return true
+}
+
+// Methods to fulfill badger interface.
+func (c Counter) Key() badger.Key {
+	return NewCounterKey(c.name)
+}
+
+func (c *Counter) SetKey(k badger.Key) {
+	tokens := strings.SplitN(string(k), ".", 3)
+	c.name = tokens[2]
+}
+
+func (c Counter) Value() ([]byte, error) {
+	return []byte(strconv.FormatUint(c.count, 10)), nil
+}
+
+func (c *Counter) SetValue(v []byte) error {
+	count, err := strconv.ParseUint(string(v), 10, 64)
+	if err != nil {
+		return err
+	}
+	c.count = count
+	return nil
+}
+
+func (c Counter) Meta() byte { return 0 }
+
+func (c Counter) SetMeta(m byte) {}
+
+func (c Counter) Expires() time.Time { return c.expires }
+
+func (c *Counter) SetExpires(t time.Time) { c.expires = t }
+
+func (c Counter) Error() string { return "" }
+
+func NewCounterKey(name string) badger.Key {
+	return badger.Key(fmt.Sprintf("%s.%s", counterKeyPrefix, name))
+}
+
+// NewCounter returns a counter for the window of the given length, which includes now.
+func NewCounter(name string, window time.Duration, now time.Time) *Counter {
+	start := now.Truncate(window)
+	return &Counter{
+		name:    fmt.Sprintf("%s.%d", name, start.Unix()),
+		expires: start.Add(window),
+	}
+}
@@ -0,0 +1,87 @@
+package ratelimits_test
+
+import (
+	badger "github.com/dgraph-io/badger/v2"
+	"github.com/onionltd/mono/services/vworp/badger/ratelimits"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestBucketTake(t *testing.T) {
+	now := time.Now()
+	b := ratelimits.NewBucket("test", 2, now)
+	assert.True(t, b.Take(2, time.Minute, now))
+	assert.True(t, b.Take(2, time.Minute, now))
+	assert.False(t, b.Take(2, time.Minute, now))
+
+	// One token is refilled every 30 seconds.
+	assert.True(t, b.Take(2, time.Minute, now.Add(30*time.Second)))
+	assert.False(t, b.Take(2, time.Minute, now.Add(30*time.Second)))
+
+	// The bucket never holds more than limit tokens.
+	assert.True(t, b.Take(2, time.Minute, now.Add(time.Hour)))
+	assert.True(t, b.Take(2, time.Minute, now.Add(time.Hour)))
+	assert.False(t, b.Take(2, time.Minute, now.Add(time.Hour)))
+}
+
+func TestTakeTxn(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	take := func() bool {
+		ok := false
+		if err := db.Update(func(txn *badger.Txn) error {
+			ok, err = ratelimits.TakeTxn(txn, "test", 3, time.Hour, now)
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+	assert.True(t, take())
+	assert.True(t, take())
+	assert.True(t, take())
+	assert.False(t, take())
+}
+
+func TestIncrementTxn(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	increment := func(now time.Time) bool {
+		ok := false
+		if err := db.Update(func(txn *badger.Txn) error {
+			ok, err = ratelimits.IncrementTxn(txn, "test", 2, time.Hour, now)
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+	assert.True(t, increment(now))
+	assert.True(t, increment(now))
+	assert.False(t, increment(now))
+
+	// The quota is renewed in the next window.
+	assert.True(t, increment(now.Add(time.Hour)))
+}
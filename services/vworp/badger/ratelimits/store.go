@@ -0,0 +1,33 @@
+package ratelimits
+
+import (
+	"errors"
+	"github.com/dgraph-io/badger/v2"
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
+	"time"
+)
+
+// TakeTxn takes a token from the bucket of the given name. False is returned if the bucket is empty.
+func TakeTxn(txn *badger.Txn, name string, limit int, period time.Duration, now time.Time) (bool, error) {
+	b := NewBucket(name, limit, now)
+	if err := badgerutil.LoadTxn(txn, b.Key(), b); err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+		return false, err
+	}
+	if !b.Take(limit, period, now) {
+		return false, nil
+	}
+	return true, badgerutil.StoreTxn(txn, b)
+}
+
+// IncrementTxn increments the counter of the given name in the current window. False is returned
+// if the quota is exhausted.
+func IncrementTxn(txn *badger.Txn, name string, quota int, window time.Duration, now time.Time) (bool, error) {
+	c := NewCounter(name, window, now)
+	if err := badgerutil.LoadTxn(txn, c.Key(), c); err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+		return false, err
+	}
+	if !c.Increment(quota) {
+		return false, nil
+	}
+	return true, badgerutil.StoreTxn(txn, c)
+}
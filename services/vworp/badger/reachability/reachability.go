@@ -0,0 +1,98 @@
+package reachability
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/onionltd/mono/pkg/utils/badger"
+	"strings"
+	"time"
+)
+
+type reachabilityBare struct {
+	Reachable  bool  `json:"reachable"`
+	StatusCode int   `json:"status_code,omitempty"`
+	CheckedAt  int64 `json:"checked_at"`
+}
+
+// Reachability holds the result of the last check of a link's path on mirrors of the service.
+type Reachability struct {
+	fingerprint string
+	reachable   bool
+	statusCode  int
+	checkedAt   time.Time
+	expires     time.Time
+}
+
+func (r Reachability) Fingerprint() string {
+	return r.fingerprint
+}
+
+// Reachable returns true if the path was reachable on at least one mirror.
+func (r Reachability) Reachable() bool {
+	return r.reachable
+}
+
+// StatusCode returns HTTP status code of the last response, zero if no mirror responded.
+func (r Reachability) StatusCode() int {
+	return r.statusCode
+}
+
+func (r Reachability) CheckedAt() time.Time {
+	return r.checkedAt
+}
+
+// Methods to fulfill badger interface.
+func (r Reachability) Key() badger.Key {
+	return NewKey(r.fingerprint)
+}
+
+func (r *Reachability) SetKey(k badger.Key) {
+	tokens := strings.SplitN(string(k), ".", 2)
+	r.fingerprint = tokens[1]
+}
+
+func (r Reachability) Value() ([]byte, error) {
+	return json.Marshal(reachabilityBare{
+		Reachable:  r.reachable,
+		StatusCode: r.statusCode,
+		CheckedAt:  r.checkedAt.Unix(),
+	})
+}
+
+func (r *Reachability) SetValue(v []byte) error {
+	bare := reachabilityBare{}
+	if err := json.Unmarshal(v, &bare); err != nil {
+		return err
+	}
+	r.reachable = bare.Reachable
+	r.statusCode = bare.StatusCode
+	r.checkedAt = time.Unix(bare.CheckedAt, 0)
+	return nil
+}
+
+func (r Reachability) Meta() byte { return 0 }
+
+func (r Reachability) SetMeta(m byte) {}
+
+func (r Reachability) Expires() time.Time { return r.expires }
+
+func (r *Reachability) SetExpires(t time.Time) { r.expires = t }
+
+func (r Reachability) Error() string { return "" }
+
+const keyPrefix = "reachability"
+
+func NewKey(fingerprint string) badger.Key {
+	return badger.Key(fmt.Sprintf("%s.%s", keyPrefix, fingerprint))
+}
+
+// NewReachability returns a result of a check. The result expires at the same time as the link,
+// once it's stored with StoreTxn.
+func NewReachability(fingerprint string, reachable bool, statusCode int, checkedAt time.Time) *Reachability {
+	return &Reachability{
+		fingerprint: fingerprint,
+		reachable:   reachable,
+		statusCode:  statusCode,
+		checkedAt:   checkedAt,
+	}
+}
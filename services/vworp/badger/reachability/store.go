@@ -0,0 +1,44 @@
+package reachability
+
+import (
+	"errors"
+	"github.com/dgraph-io/badger/v2"
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
+	"github.com/onionltd/mono/services/vworp/badger/links"
+)
+
+func Load(db *badger.DB, fingerprint string) (*Reachability, error) {
+	r := &Reachability{}
+	if err := badgerutil.Load(db, NewKey(fingerprint), r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// StoreTxn writes the result of a check, so that it expires together with the link as it's stored now.
+// Nothing is written if the link no longer exists.
+func StoreTxn(txn *badger.Txn, r *Reachability) error {
+	link := &links.Link{}
+	if err := badgerutil.LoadTxn(txn, links.NewKey(r.fingerprint), link); err != nil {
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		return err
+	}
+	r.expires = link.Expires()
+	return badgerutil.StoreTxn(txn, r)
+}
+
+// ExtendTxn makes the result of the last check of the link expire together with the link,
+// whose lifetime may have been extended.
+func ExtendTxn(txn *badger.Txn, link *links.Link) error {
+	r := &Reachability{}
+	if err := badgerutil.LoadTxn(txn, NewKey(link.Fingerprint()), r); err != nil {
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		return err
+	}
+	r.expires = link.Expires()
+	return badgerutil.StoreTxn(txn, r)
+}
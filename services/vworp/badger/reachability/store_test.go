@@ -0,0 +1,122 @@
+package reachability_test
+
+import (
+	badger "github.com/dgraph-io/badger/v2"
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
+	"github.com/onionltd/mono/services/vworp/badger/links"
+	"github.com/onionltd/mono/services/vworp/badger/reachability"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestStoreTxn(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store := func(r *reachability.Reachability) error {
+		return db.Update(func(txn *badger.Txn) error {
+			return reachability.StoreTxn(txn, r)
+		})
+	}
+
+	link, err := links.NewLink("example", "/article/why-birds-flap-their-wings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	link.SetExpiresAt(time.Now().Add(24 * time.Hour))
+
+	// Nothing is stored for a link that doesn't exist.
+	checkedAt := time.Now().Truncate(time.Second)
+	r := reachability.NewReachability(link.Fingerprint(), true, http.StatusOK, checkedAt)
+	if err := store(r); err != nil {
+		t.Fatal(err)
+	}
+	_, err = reachability.Load(db, link.Fingerprint())
+	assert.Equal(t, badger.ErrKeyNotFound, err)
+
+	if err := links.Store(db, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := store(r); err != nil {
+		t.Fatal(err)
+	}
+	readR, err := reachability.Load(db, link.Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, readR.Reachable())
+	assert.Equal(t, http.StatusOK, readR.StatusCode())
+	assert.Equal(t, checkedAt, readR.CheckedAt())
+	assert.Equal(t, link.Expires().Unix(), readR.Expires().Unix())
+}
+
+func TestExtendTxn(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	link, err := links.NewLink("example", "/article/why-birds-flap-their-wings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	link.SetExpiresAt(time.Now().Add(24 * time.Hour))
+	if err := links.Store(db, link); err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Update(func(txn *badger.Txn) error {
+		r := reachability.NewReachability(link.Fingerprint(), false, http.StatusNotFound, time.Now())
+		return reachability.StoreTxn(txn, r)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	link.SetExpiresAt(time.Now().Add(7 * 24 * time.Hour))
+	err = db.Update(func(txn *badger.Txn) error {
+		if err := badgerutil.StoreTxn(txn, link); err != nil {
+			return err
+		}
+		return reachability.ExtendTxn(txn, link)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readR, err := reachability.Load(db, link.Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, readR.Reachable())
+	assert.Equal(t, link.Expires().Unix(), readR.Expires().Unix())
+
+	// Results of links that were never checked are left alone.
+	other, err := links.NewLink("example", "/article/why-fish-swim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(txn *badger.Txn) error {
+		return reachability.ExtendTxn(txn, other)
+	})
+	assert.NoError(t, err)
+	_, err = reachability.Load(db, other.Fingerprint())
+	assert.Equal(t, badger.ErrKeyNotFound, err)
+}
@@ -0,0 +1,187 @@
+package reports
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/onionltd/mono/pkg/utils/badger"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	IDLength         = 8
+	CommentMaxLength = 1000
+)
+
+var (
+	ErrInvalidCategory = errors.New("invalid category")
+	ErrCommentTooLong  = errors.New("comment is too long")
+)
+
+// Category is a reason a link was reported for.
+type Category string
+
+const (
+	CategoryPhishing Category = "phishing"
+	CategoryMalware  Category = "malware"
+	CategoryScam     Category = "scam"
+	CategoryIllegal  Category = "illegal"
+	CategoryOther    Category = "other"
+)
+
+// Categories lists all categories in the order they are offered to users.
+var Categories = []Category{
+	CategoryPhishing,
+	CategoryMalware,
+	CategoryScam,
+	CategoryIllegal,
+	CategoryOther,
+}
+
+// categoryLabels are names of the categories as they are shown to users.
+var categoryLabels = map[Category]string{
+	CategoryPhishing: "phishing",
+	CategoryMalware:  "malware",
+	CategoryScam:     "scam",
+	CategoryIllegal:  "illegal content",
+	CategoryOther:    "something else",
+}
+
+// Label returns a human-readable name of the category.
+func (c Category) Label() string {
+	if label, ok := categoryLabels[c]; ok {
+		return label
+	}
+	return string(c)
+}
+
+func (c Category) Valid() bool {
+	for _, category := range Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+type reportBare struct {
+	ServiceID string   `json:"service_id"`
+	Category  Category `json:"category"`
+	Comment   string   `json:"comment,omitempty"`
+	CreatedAt int64    `json:"created_at"`
+}
+
+// Report is a complaint about a link submitted by a visitor.
+type Report struct {
+	fingerprint string
+	id          string
+	serviceID   string
+	category    Category
+	comment     string
+	createdAt   time.Time
+}
+
+func (r Report) Fingerprint() string {
+	return r.fingerprint
+}
+
+func (r Report) ID() string {
+	return r.id
+}
+
+// ServiceID is kept with the report, so that it can be reviewed after the link is deleted.
+func (r Report) ServiceID() string {
+	return r.serviceID
+}
+
+func (r Report) Category() Category {
+	return r.category
+}
+
+func (r Report) Comment() string {
+	return r.comment
+}
+
+func (r Report) CreatedAt() time.Time {
+	return r.createdAt
+}
+
+// Methods to fulfill badger interface.
+func (r Report) Key() badger.Key {
+	return NewKey(r.fingerprint, r.id)
+}
+
+func (r *Report) SetKey(k badger.Key) {
+	tokens := strings.SplitN(string(k), ".", 3)
+	r.fingerprint = tokens[1]
+	r.id = tokens[2]
+}
+
+func (r Report) Value() ([]byte, error) {
+	return json.Marshal(reportBare{
+		ServiceID: r.serviceID,
+		Category:  r.category,
+		Comment:   r.comment,
+		CreatedAt: r.createdAt.Unix(),
+	})
+}
+
+func (r *Report) SetValue(v []byte) error {
+	bare := reportBare{}
+	if err := json.Unmarshal(v, &bare); err != nil {
+		return err
+	}
+	r.serviceID = bare.ServiceID
+	r.category = bare.Category
+	r.comment = bare.Comment
+	r.createdAt = time.Unix(bare.CreatedAt, 0)
+	return nil
+}
+
+func (r Report) Meta() byte { return 0 }
+
+func (r Report) SetMeta(m byte) {}
+
+func (r Report) Expires() time.Time { return time.Time{} }
+
+func (r Report) SetExpires(t time.Time) {}
+
+func (r Report) Error() string { return "" }
+
+const keyPrefix = "reports"
+
+func NewKey(fingerprint, id string) badger.Key {
+	return badger.Key(fmt.Sprintf("%s.%s", newPrefix(fingerprint), id))
+}
+
+func newPrefix(fingerprint string) string {
+	return fmt.Sprintf("%s.%s", keyPrefix, fingerprint)
+}
+
+// NewReport validates the category and the comment and returns a report with a random ID.
+func NewReport(fingerprint, serviceID string, category Category, comment string) (*Report, error) {
+	if !category.Valid() {
+		return nil, ErrInvalidCategory
+	}
+	comment = strings.TrimSpace(comment)
+	if utf8.RuneCountInString(comment) > CommentMaxLength {
+		return nil, ErrCommentTooLong
+	}
+
+	b := make([]byte, IDLength)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return &Report{
+		fingerprint: fingerprint,
+		id:          hex.EncodeToString(b),
+		serviceID:   serviceID,
+		category:    category,
+		comment:     comment,
+		createdAt:   time.Now(),
+	}, nil
+}
@@ -0,0 +1,73 @@
+package reports_test
+
+import (
+	badger "github.com/dgraph-io/badger/v2"
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
+	"github.com/onionltd/mono/services/vworp/badger/reports"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestQueue(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = reports.NewReport("abcdef", "svc", "boring", "")
+	assert.Equal(t, reports.ErrInvalidCategory, err)
+	_, err = reports.NewReport("abcdef", "svc", reports.CategoryOther, strings.Repeat("x", reports.CommentMaxLength+1))
+	assert.Equal(t, reports.ErrCommentTooLong, err)
+
+	add := func(fingerprint string, category reports.Category) {
+		r, err := reports.NewReport(fingerprint, "svc", category, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := badgerutil.Store(db, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("abcdef", reports.CategoryPhishing)
+	add("abcdef", reports.CategoryPhishing)
+	add("abcdef", reports.CategoryScam)
+	// Reports of other links must not be mixed in.
+	add("abcdef01", reports.CategoryMalware)
+
+	queue, err := reports.Queue(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, queue, 2) {
+		assert.Equal(t, "abcdef", queue[0].Fingerprint)
+		assert.Equal(t, 3, queue[0].Count)
+		assert.Equal(t, 2, queue[0].Categories[reports.CategoryPhishing])
+		assert.Equal(t, 1, queue[1].Count)
+	}
+
+	n, err := reports.Dismiss(db, "abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3, n)
+
+	rs, err := reports.Load(db, "abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, rs)
+
+	rs, err = reports.Load(db, "abcdef01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, rs, 1)
+}
@@ -0,0 +1,113 @@
+package reports
+
+import (
+	"github.com/dgraph-io/badger/v2"
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
+	"sort"
+	"time"
+)
+
+// Summary aggregates reports of a single link.
+type Summary struct {
+	Fingerprint    string
+	ServiceID      string
+	Count          int
+	Categories     map[Category]int
+	LastReportedAt time.Time
+}
+
+// Load returns all reports of the link, the oldest first.
+func Load(db *badger.DB, fingerprint string) ([]*Report, error) {
+	result := []*Report{}
+	err := db.View(func(txn *badger.Txn) error {
+		return forEachTxn(txn, []byte(newPrefix(fingerprint)+"."), func(r *Report) error {
+			result = append(result, r)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].createdAt.Before(result[j].createdAt)
+	})
+	return result, nil
+}
+
+// Dismiss removes all reports of the link and returns how many were removed.
+func Dismiss(db *badger.DB, fingerprint string) (int, error) {
+	n := 0
+	err := db.Update(func(txn *badger.Txn) error {
+		keys := []badgerutil.Key{}
+		err := forEachTxn(txn, []byte(newPrefix(fingerprint)+"."), func(r *Report) error {
+			keys = append(keys, r.Key())
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := txn.Delete(k); err != nil {
+				return err
+			}
+		}
+		n = len(keys)
+		return nil
+	})
+	return n, err
+}
+
+// Queue returns summaries of all reported links, the most reported links first.
+func Queue(db *badger.DB) ([]*Summary, error) {
+	summaries := map[string]*Summary{}
+	err := db.View(func(txn *badger.Txn) error {
+		return forEachTxn(txn, []byte(keyPrefix+"."), func(r *Report) error {
+			s, ok := summaries[r.fingerprint]
+			if !ok {
+				s = &Summary{
+					Fingerprint: r.fingerprint,
+					ServiceID:   r.serviceID,
+					Categories:  map[Category]int{},
+				}
+				summaries[r.fingerprint] = s
+			}
+			s.Count++
+			s.Categories[r.category]++
+			if r.createdAt.After(s.LastReportedAt) {
+				s.LastReportedAt = r.createdAt
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Summary, 0, len(summaries))
+	for _, s := range summaries {
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].LastReportedAt.After(result[j].LastReportedAt)
+	})
+	return result, nil
+}
+
+func forEachTxn(txn *badger.Txn, prefix []byte, fn func(r *Report) error) error {
+	it := txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		r := &Report{}
+		if err := badgerutil.LoadTxn(txn, it.Item().KeyCopy(nil), r); err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package slugs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/onionltd/mono/pkg/utils/badger"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	MinLength = 3
+	MaxLength = 64
+)
+
+var (
+	ErrInvalid  = errors.New("invalid slug")
+	ErrReserved = errors.New("slug is reserved")
+	ErrTaken    = errors.New("slug is already taken")
+)
+
+// reserved lists slugs that could be confused with vworp! pages.
+var reserved = map[string]struct{}{
+	"about":   {},
+	"admin":   {},
+	"api":     {},
+	"backup":  {},
+	"help":    {},
+	"links":   {},
+	"manage":  {},
+	"metrics": {},
+	"new":     {},
+	"oops":    {},
+	"preview": {},
+	"privacy": {},
+	"sorry":   {},
+	"static":  {},
+	"vworp":   {},
+}
+
+var (
+	slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	// Slugs must be distinguishable from hex encoded fingerprints.
+	hexPattern = regexp.MustCompile(`^[a-f0-9]+$`)
+)
+
+// Normalize returns the slug in its canonical form.
+func Normalize(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// Validate checks the slug is in its canonical form and is allowed to be used.
+func Validate(s string) error {
+	if len(s) < MinLength || len(s) > MaxLength {
+		return ErrInvalid
+	}
+	if !slugPattern.MatchString(s) || hexPattern.MatchString(s) {
+		return ErrInvalid
+	}
+	if _, ok := reserved[s]; ok {
+		return ErrReserved
+	}
+	return nil
+}
+
+// IsSlug returns true if s can't be a fingerprint.
+func IsSlug(s string) bool {
+	return !hexPattern.MatchString(s)
+}
+
+// Slug maps a human-readable name to a fingerprint of a link.
+type Slug struct {
+	name        string
+	fingerprint string
+	expires     time.Time
+}
+
+func (s Slug) Name() string {
+	return s.name
+}
+
+func (s Slug) Fingerprint() string {
+	return s.fingerprint
+}
+
+// Methods to fulfill badger interface.
+func (s Slug) Key() badger.Key {
+	return NewKey(s.name)
+}
+
+func (s *Slug) SetKey(k badger.Key) {
+	tokens := strings.SplitN(string(k), ".", 2)
+	s.name = tokens[1]
+}
+
+func (s Slug) Value() ([]byte, error) {
+	return []byte(s.fingerprint), nil
+}
+
+func (s *Slug) SetValue(v []byte) error {
+	s.fingerprint = string(v)
+	return nil
+}
+
+func (s Slug) Meta() byte { return 0 }
+
+func (s Slug) SetMeta(m byte) {}
+
+func (s Slug) Expires() time.Time { return s.expires }
+
+func (s *Slug) SetExpires(t time.Time) { s.expires = t }
+
+func (s Slug) Error() string { return "" }
+
+const keyPrefix = "slugs"
+
+func NewKey(name string) badger.Key {
+	return badger.Key(fmt.Sprintf("%s.%s", keyPrefix, name))
+}
+
+// NewSlug returns a slug pointing to a fingerprint. The slug is removed from the database
+// at the same time as the link.
+func NewSlug(name, fingerprint string, expires time.Time) *Slug {
+	return &Slug{
+		name:        name,
+		fingerprint: fingerprint,
+		expires:     expires,
+	}
+}
@@ -0,0 +1,68 @@
+package slugs_test
+
+import (
+	badger "github.com/dgraph-io/badger/v2"
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
+	"github.com/onionltd/mono/services/vworp/badger/links"
+	"github.com/onionltd/mono/services/vworp/badger/slugs"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, slugs.Validate("why-birds-flap"))
+	assert.NoError(t, slugs.Validate("birds2020"))
+	assert.Equal(t, slugs.ErrReserved, slugs.Validate("oops"))
+	assert.Equal(t, slugs.ErrInvalid, slugs.Validate("ab"))
+	assert.Equal(t, slugs.ErrInvalid, slugs.Validate("-birds"))
+	assert.Equal(t, slugs.ErrInvalid, slugs.Validate("Birds"))
+	assert.Equal(t, slugs.ErrInvalid, slugs.Validate("bird_s"))
+	// Hex strings are reserved for fingerprints.
+	assert.Equal(t, slugs.ErrInvalid, slugs.Validate("deadbeef"))
+}
+
+func TestStoreTxn(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	link, err := links.NewLink("example", "/article/why-birds-flap-their-wings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := badgerutil.Store(db, link); err != nil {
+		t.Fatal(err)
+	}
+
+	store := func(s *slugs.Slug) error {
+		return db.Update(func(txn *badger.Txn) error {
+			return slugs.StoreTxn(txn, s)
+		})
+	}
+
+	if err := store(slugs.NewSlug("birds", link.Fingerprint(), time.Time{})); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, slugs.ErrTaken, store(slugs.NewSlug("birds", "0123456789", time.Time{})))
+
+	slug, err := slugs.Load(db, "birds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, link.Fingerprint(), slug.Fingerprint())
+
+	// Slugs of deleted links can be reused.
+	if err := links.Delete(db, link); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, store(slugs.NewSlug("birds", "0123456789", time.Time{})))
+}
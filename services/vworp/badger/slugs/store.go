@@ -0,0 +1,35 @@
+package slugs
+
+import (
+	"errors"
+	"github.com/dgraph-io/badger/v2"
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
+	"github.com/onionltd/mono/services/vworp/badger/links"
+)
+
+// StoreTxn writes the slug to the database, unless the slug already points to another fingerprint.
+// A slug pointing to a link that no longer exists can be reused.
+func StoreTxn(txn *badger.Txn, s *Slug) error {
+	existing := &Slug{}
+	err := badgerutil.LoadTxn(txn, s.Key(), existing)
+	if err == nil && existing.fingerprint != s.fingerprint {
+		_, err := txn.Get(links.NewKey(existing.fingerprint))
+		if err == nil {
+			return ErrTaken
+		}
+		if !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+	} else if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+		return err
+	}
+	return badgerutil.StoreTxn(txn, s)
+}
+
+func Load(db *badger.DB, name string) (*Slug, error) {
+	s := &Slug{}
+	if err := badgerutil.Load(db, NewKey(name), s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,92 @@
+package stats
+
+import (
+	"fmt"
+	"github.com/onionltd/mono/pkg/utils/badger"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dayFormat = "20060102"
+
+// DayCount holds the number of redirects of a link within a single day (UTC). Nothing
+// about the visitors is recorded.
+type DayCount struct {
+	fingerprint string
+	day         time.Time
+	count       uint64
+	expires     time.Time
+}
+
+func (d DayCount) Fingerprint() string {
+	return d.fingerprint
+}
+
+func (d DayCount) Day() time.Time {
+	return d.day
+}
+
+func (d DayCount) Count() uint64 {
+	return d.count
+}
+
+func (d *DayCount) Increment() {
+	d.count++
+}
+
+// Methods to fulfill badger interface.
+func (d DayCount) Key() badger.Key {
+	return NewKey(d.fingerprint, d.day)
+}
+
+func (d *DayCount) SetKey(k badger.Key) {
+	tokens := strings.SplitN(string(k), ".", 3)
+	d.fingerprint = tokens[1]
+	d.day, _ = time.Parse(dayFormat, tokens[2])
+}
+
+func (d DayCount) Value() ([]byte, error) {
+	return []byte(strconv.FormatUint(d.count, 10)), nil
+}
+
+func (d *DayCount) SetValue(v []byte) error {
+	count, err := strconv.ParseUint(string(v), 10, 64)
+	if err != nil {
+		return err
+	}
+	d.count = count
+	return nil
+}
+
+func (d DayCount) Meta() byte { return 0 }
+
+func (d DayCount) SetMeta(m byte) {}
+
+func (d DayCount) Expires() time.Time { return d.expires }
+
+func (d *DayCount) SetExpires(t time.Time) { d.expires = t }
+
+func (d DayCount) Error() string { return "" }
+
+const keyPrefix = "stats"
+
+func NewKey(fingerprint string, day time.Time) badger.Key {
+	return badger.Key(fmt.Sprintf("%s.%s", NewPrefix(fingerprint), day.UTC().Format(dayFormat)))
+}
+
+// NewPrefix returns a prefix of keys of all day counts of the link.
+func NewPrefix(fingerprint string) badger.Key {
+	return badger.Key(fmt.Sprintf("%s.%s", keyPrefix, fingerprint))
+}
+
+// NewDayCount returns an empty count for the day, which includes t. The count is kept for
+// retention after the day ends.
+func NewDayCount(fingerprint string, t time.Time, retention time.Duration) *DayCount {
+	day := t.UTC().Truncate(24 * time.Hour)
+	return &DayCount{
+		fingerprint: fingerprint,
+		day:         day,
+		expires:     day.Add(24 * time.Hour).Add(retention),
+	}
+}
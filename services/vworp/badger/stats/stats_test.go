@@ -0,0 +1,67 @@
+package stats_test
+
+import (
+	badger "github.com/dgraph-io/badger/v2"
+	"github.com/onionltd/mono/services/vworp/badger/stats"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestIncrement(t *testing.T) {
+	const retention = 24 * time.Hour
+
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	increment := func(fingerprint string, t time.Time) error {
+		return db.Update(func(txn *badger.Txn) error {
+			return stats.IncrementTxn(txn, fingerprint, t, retention)
+		})
+	}
+	for _, ts := range []time.Time{now, now, now.Add(-24 * time.Hour)} {
+		if err := increment("abcdef", ts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Counts of other links must not be mixed in.
+	if err := increment("abcdef01", now); err != nil {
+		t.Fatal(err)
+	}
+
+	days, err := stats.Load(db, "abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, days, 2)
+	assert.Equal(t, uint64(1), days[0].Count())
+	assert.Equal(t, uint64(2), days[1].Count())
+	assert.Equal(t, uint64(3), stats.Total(days))
+
+	if err := db.Update(func(txn *badger.Txn) error {
+		return stats.DeleteTxn(txn, "abcdef")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	days, err = stats.Load(db, "abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, days)
+
+	days, err = stats.Load(db, "abcdef01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint64(1), stats.Total(days))
+}
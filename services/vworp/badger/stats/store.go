@@ -0,0 +1,68 @@
+package stats
+
+import (
+	"errors"
+	"github.com/dgraph-io/badger/v2"
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
+	"time"
+)
+
+// IncrementTxn counts a redirect of the link at time t.
+func IncrementTxn(txn *badger.Txn, fingerprint string, t time.Time, retention time.Duration) error {
+	d := NewDayCount(fingerprint, t, retention)
+	if err := badgerutil.LoadTxn(txn, d.Key(), d); err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+		return err
+	}
+	d.Increment()
+	return badgerutil.StoreTxn(txn, d)
+}
+
+// Load returns all day counts of the link, oldest first.
+func Load(db *badger.DB, fingerprint string) ([]*DayCount, error) {
+	days := []*DayCount{}
+	err := db.View(func(txn *badger.Txn) error {
+		prefix := append(NewPrefix(fingerprint), '.')
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			d := &DayCount{}
+			if err := badgerutil.LoadTxn(txn, it.Item().KeyCopy(nil), d); err != nil {
+				return err
+			}
+			days = append(days, d)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return days, nil
+}
+
+// DeleteTxn removes all day counts of the link.
+func DeleteTxn(txn *badger.Txn, fingerprint string) error {
+	prefix := append(NewPrefix(fingerprint), '.')
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	it := txn.NewIterator(opts)
+	keys := [][]byte{}
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		keys = append(keys, it.Item().KeyCopy(nil))
+	}
+	it.Close()
+	for _, k := range keys {
+		if err := txn.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Total sums up day counts.
+func Total(days []*DayCount) uint64 {
+	total := uint64(0)
+	for _, d := range days {
+		total += d.Count()
+	}
+	return total
+}
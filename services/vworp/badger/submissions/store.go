@@ -0,0 +1,29 @@
+package submissions
+
+import (
+	"github.com/dgraph-io/badger/v2"
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
+)
+
+func Load(db *badger.DB, id string) (*Submission, error) {
+	s := &Submission{}
+	if err := badgerutil.Load(db, NewKey(id), s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Take loads the submission and removes it from the database, so it can be used only once.
+func Take(db *badger.DB, id string) (*Submission, error) {
+	s := &Submission{}
+	err := db.Update(func(txn *badger.Txn) error {
+		if err := badgerutil.LoadTxn(txn, NewKey(id), s); err != nil {
+			return err
+		}
+		return txn.Delete(s.Key())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,80 @@
+package submissions_test
+
+import (
+	badger "github.com/dgraph-io/badger/v2"
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
+	"github.com/onionltd/mono/services/vworp/badger/submissions"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestTake(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	values := url.Values{}
+	values.Set("link", "http://example.onion/article")
+	values.Set("continue", "/links/new")
+
+	sub, err := submissions.NewSubmission(values, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := badgerutil.Store(db, sub); err != nil {
+		t.Fatal(err)
+	}
+
+	readSub, err := submissions.Load(db, sub.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, values, readSub.Values())
+
+	readSub, err = submissions.Take(db, sub.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sub.ID(), readSub.ID())
+	assert.Equal(t, values, readSub.Values())
+
+	// Submission can be taken only once.
+	_, err = submissions.Take(db, sub.ID())
+	assert.Equal(t, badger.ErrKeyNotFound, err)
+	_, err = submissions.Load(db, sub.ID())
+	assert.Equal(t, badger.ErrKeyNotFound, err)
+}
+
+func TestTakeExpired(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	sub, err := submissions.NewSubmission(url.Values{}, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := badgerutil.Store(db, sub); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = submissions.Take(db, sub.ID())
+	assert.Equal(t, badger.ErrKeyNotFound, err)
+}
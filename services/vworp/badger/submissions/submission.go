@@ -0,0 +1,81 @@
+package submissions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/onionltd/mono/pkg/utils/badger"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const IDLength = 16
+
+// Submission holds form values while the user is solving a challenge, so the values
+// don't have to travel through redirects.
+type Submission struct {
+	id      string
+	values  url.Values
+	expires time.Time
+}
+
+func (s Submission) ID() string {
+	return s.id
+}
+
+func (s Submission) Values() url.Values {
+	return s.values
+}
+
+// Methods to fulfill badger interface.
+func (s Submission) Key() badger.Key {
+	return NewKey(s.id)
+}
+
+func (s *Submission) SetKey(k badger.Key) {
+	tokens := strings.SplitN(string(k), ".", 2)
+	s.id = tokens[1]
+}
+
+func (s Submission) Value() ([]byte, error) {
+	return []byte(s.values.Encode()), nil
+}
+
+func (s *Submission) SetValue(v []byte) error {
+	values, err := url.ParseQuery(string(v))
+	if err != nil {
+		return err
+	}
+	s.values = values
+	return nil
+}
+
+func (s Submission) Meta() byte { return 0 }
+
+func (s Submission) SetMeta(m byte) {}
+
+func (s Submission) Expires() time.Time { return s.expires }
+
+func (s *Submission) SetExpires(t time.Time) { s.expires = t }
+
+func (s Submission) Error() string { return "" }
+
+const keyPrefix = "submissions"
+
+func NewKey(id string) badger.Key {
+	return badger.Key(fmt.Sprintf("%s.%s", keyPrefix, id))
+}
+
+// NewSubmission returns a submission with a random ID.
+func NewSubmission(values url.Values, expires time.Time) (*Submission, error) {
+	b := make([]byte, IDLength)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return &Submission{
+		id:      hex.EncodeToString(b),
+		values:  values,
+		expires: expires,
+	}, nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	baseconfig "github.com/onionltd/mono/pkg/config"
 	"time"
 )
@@ -8,12 +9,52 @@ import (
 type config struct {
 	baseconfig.BaseConfig
 
-	WWWDir                string                `long:"www" description:"WWW resources directory" required:"yes" env:"WWW_PATH"`
-	TemplatesDir          string                `long:"templates" description:"Templates directory" required:"yes" env:"TEMPLATES_PATH"`
-	OnionTreeDir          string                `long:"oniontree" description:"OnionTree directory" required:"yes" env:"ONIONTREE_PATH"`
-	BadgerDBDir           string                `long:"badgerdb" description:"Badger DB directory" required:"yes" env:"BADGERDB_PATH"`
-	MonitorConnectionsMax int64                 `long:"monitor-connections-max" description:"Maximum parallel connections" default:"255" env:"MONITOR_CONNECTIONS_MAX"`
-	MonitorPingTimeout    time.Duration         `long:"monitor-ping-timeout" description:"Maximum time before timeout" default:"15s" env:"MONITOR_PING_TIMEOUT"`
-	MonitorPingInterval   time.Duration         `long:"monitor-ping-interval" description:"Ping in intervals" default:"1m" env:"MONITOR_PING_INTERVAL"`
-	BackupAuth            baseconfig.AuthString `long:"backup-auth" description:"Protect backup endpoints with username:password" required:"no" env:"BACKUP_AUTH"`
+	WWWDir                    string                `long:"www" description:"WWW resources directory" required:"yes" env:"WWW_PATH"`
+	TemplatesDir              string                `long:"templates" description:"Templates directory" required:"yes" env:"TEMPLATES_PATH"`
+	LocalesDir                string                `long:"locales" description:"Message catalogs directory" required:"no" env:"LOCALES_PATH"`
+	OnionTreeDir              string                `long:"oniontree" description:"OnionTree directory" required:"yes" env:"ONIONTREE_PATH"`
+	BadgerDBDir               string                `long:"badgerdb" description:"Badger DB directory" required:"yes" env:"BADGERDB_PATH"`
+	BadgerGCInterval          time.Duration         `long:"badgerdb-gc-interval" description:"Run value log garbage collection in intervals, 0 disables the collection" default:"10m" env:"BADGERDB_GC_INTERVAL"`
+	BadgerGCDiscardRatio      float64               `long:"badgerdb-gc-discard-ratio" description:"Rewrite value log files with at least the ratio of discardable data" default:"0.5" env:"BADGERDB_GC_DISCARD_RATIO"`
+	MonitorConnectionsMax     int64                 `long:"monitor-connections-max" description:"Maximum parallel connections" default:"255" env:"MONITOR_CONNECTIONS_MAX"`
+	MonitorPingTimeout        time.Duration         `long:"monitor-ping-timeout" description:"Maximum time before timeout" default:"15s" env:"MONITOR_PING_TIMEOUT"`
+	MonitorPingInterval       time.Duration         `long:"monitor-ping-interval" description:"Ping in intervals" default:"1m" env:"MONITOR_PING_INTERVAL"`
+	LinkCheckInterval         time.Duration         `long:"link-check-interval" description:"Check reachability of links in intervals, 0 disables the checks" default:"6h" env:"LINK_CHECK_INTERVAL"`
+	LinkCheckTimeout          time.Duration         `long:"link-check-timeout" description:"Maximum time before a link check times out" default:"30s" env:"LINK_CHECK_TIMEOUT"`
+	TorProxy                  string                `long:"tor-proxy" description:"Check links over Tor SOCKS proxy at host:port, ALL_PROXY is used if not set" required:"no" env:"TOR_PROXY"`
+	MirrorStrategy            string                `long:"mirror-strategy" description:"Select a mirror to redirect to" choice:"first" choice:"random" choice:"round-robin" choice:"least-recently-failed" default:"first" env:"MIRROR_STRATEGY"`
+	CaptchaStore              string                `long:"captcha-store" description:"Where to keep captcha answers" choice:"memory" choice:"badger" default:"memory" env:"CAPTCHA_STORE"`
+	CaptchaTTL                time.Duration         `long:"captcha-ttl" description:"Time to solve captcha challenge" default:"10m" env:"CAPTCHA_TTL"`
+	RateLimitGlobal           int                   `long:"rate-limit-global" description:"Number of links that can be created per minute, 0 disables the limit" default:"60" env:"RATE_LIMIT_GLOBAL"`
+	RateLimitSession          int                   `long:"rate-limit-session" description:"Number of links that can be created per hour in a single session, 0 disables the limit" default:"20" env:"RATE_LIMIT_SESSION"`
+	RateLimitService          int                   `long:"rate-limit-service" description:"Number of links to a single service that can be created per day, 0 disables the limit" default:"500" env:"RATE_LIMIT_SERVICE"`
+	NoStats                   bool                  `long:"no-stats" description:"Do not count redirects of links" env:"NO_STATS"`
+	StatsRetention            time.Duration         `long:"stats-retention" description:"Keep daily redirect counts for the duration" default:"2160h" env:"STATS_RETENTION"`
+	ChallengeMode             string                `long:"challenge" description:"Protect forms with a challenge" choice:"captcha" choice:"pow" default:"captcha" env:"CHALLENGE_MODE"`
+	PoWDifficulty             int                   `long:"pow-difficulty" description:"Number of leading zero bits required by proof-of-work challenge" default:"20" env:"POW_DIFFICULTY"`
+	PoWTTL                    time.Duration         `long:"pow-ttl" description:"Time to solve proof-of-work challenge" default:"10m" env:"POW_TTL"`
+	BackupAuth                baseconfig.AuthString `long:"backup-auth" description:"Protect backup endpoints with username:password" required:"no" env:"BACKUP_AUTH"`
+	BackupDir                 string                `long:"backup-dir" description:"Write scheduled backups to the directory" required:"no" env:"BACKUP_PATH"`
+	BackupFullInterval        time.Duration         `long:"backup-full-interval" description:"Make a full backup in intervals" default:"24h" env:"BACKUP_FULL_INTERVAL"`
+	BackupIncrementalInterval time.Duration         `long:"backup-incremental-interval" description:"Make an incremental backup in intervals" default:"1h" env:"BACKUP_INCREMENTAL_INTERVAL"`
+	BackupRetention           int                   `long:"backup-retention" description:"Keep the number of full backups and their incremental backups" default:"7" env:"BACKUP_RETENTION"`
+	BackupMaxUploadSize       int64                 `long:"backup-max-upload-size" description:"Maximum size of a restored backup in bytes" default:"1073741824" env:"BACKUP_MAX_UPLOAD_SIZE"`
+	AdminAuth                 baseconfig.AuthString `long:"admin-auth" description:"Enable admin pages and protect them with a key" required:"no" env:"ADMIN_AUTH"`
+	APIAuth                   baseconfig.AuthString `long:"api-auth" description:"Enable JSON API and protect it with a key" required:"no" env:"API_AUTH"`
+}
+
+// validate checks values the parser can't check on its own.
+func (c config) validate() error {
+	if c.BackupDir != "" {
+		if c.BackupRetention <= 0 {
+			return errors.New("--backup-retention must be greater than zero")
+		}
+		if c.BackupFullInterval <= 0 {
+			return errors.New("--backup-full-interval must be greater than zero")
+		}
+		if c.BackupIncrementalInterval <= 0 {
+			return errors.New("--backup-incremental-interval must be greater than zero")
+		}
+	}
+	return nil
 }
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"github.com/dgraph-io/badger/v2"
+	"github.com/onionltd/mono/services/vworp/badger/links"
+	"github.com/onionltd/mono/services/vworp/badger/reachability"
+	"github.com/oniontree-org/go-oniontree/scanner/evtcache"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"golang.org/x/net/proxy"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// linkCheckStartDelay gives the scanner time to find online mirrors.
+	linkCheckStartDelay = 5 * time.Minute
+	// linkCheckWorkers limits how many links are checked in parallel.
+	linkCheckWorkers = 8
+)
+
+// linkChecker periodically checks that paths of stored links are reachable on online mirrors.
+// Requests are made over the configured Tor SOCKS proxy. If there's none, the proxy configured
+// in the environment (ALL_PROXY) is used, same as the scanner does.
+type linkChecker struct {
+	logger *zap.Logger
+	db     *badger.DB
+	// lock is held while the database is accessed.
+	lock   sync.Locker
+	cache  *evtcache.Cache
+	client *http.Client
+
+	interval time.Duration
+	timeout  time.Duration
+
+	checks *prometheus.CounterVec
+}
+
+func (l *linkChecker) Run(ctx context.Context) error {
+	t := linkCheckStartDelay
+	for {
+		select {
+		case <-time.After(t):
+			if err := l.checkAll(ctx); err != nil {
+				l.logger.Error("link check failed", zap.Error(err))
+			}
+			t = l.interval
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (l *linkChecker) checkAll(ctx context.Context) error {
+	targets := []*links.Link{}
+	l.lock.Lock()
+	err := l.db.View(func(txn *badger.Txn) error {
+		return links.ForEachTxn(txn, func(link *links.Link) error {
+			if !link.Expired() {
+				targets = append(targets, link)
+			}
+			return nil
+		})
+	})
+	l.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	sem := make(chan struct{}, linkCheckWorkers)
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+	for _, link := range targets {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil
+		}
+		wg.Add(1)
+		go func(link *links.Link) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			l.check(ctx, link)
+		}(link)
+	}
+	return nil
+}
+
+// check requests the link's path on online mirrors until one of them responds successfully.
+func (l *linkChecker) check(ctx context.Context, link *links.Link) {
+	online, _ := l.cache.GetOnlineAddresses(link.ServiceID())
+	// Nothing can be learned about the path, if the service is offline.
+	if len(online) == 0 {
+		return
+	}
+	sortAddresses(online)
+
+	reachable := false
+	statusCode := 0
+	for _, addr := range online {
+		code, err := l.request(ctx, addr+link.Path())
+		if err != nil {
+			continue
+		}
+		statusCode = code
+		if code < http.StatusBadRequest {
+			reachable = true
+			break
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	result := "unreachable"
+	if reachable {
+		result = "reachable"
+	}
+	l.checks.WithLabelValues(result).Inc()
+
+	// The link may have been extended or deleted while it was being checked.
+	r := reachability.NewReachability(link.Fingerprint(), reachable, statusCode, time.Now())
+	l.lock.Lock()
+	err := l.db.Update(func(txn *badger.Txn) error {
+		return reachability.StoreTxn(txn, r)
+	})
+	l.lock.Unlock()
+	if err != nil {
+		l.logger.Error("failed to store reachability", zap.Error(err))
+	}
+}
+
+// request returns HTTP status code of the URL. HEAD request is tried first, since the body is not needed.
+func (l *linkChecker) request(ctx context.Context, url string) (int, error) {
+	code, err := l.do(ctx, http.MethodHead, url)
+	if err != nil {
+		return 0, err
+	}
+	if code == http.StatusMethodNotAllowed || code == http.StatusNotImplemented {
+		return l.do(ctx, http.MethodGet, url)
+	}
+	return code, nil
+}
+
+func (l *linkChecker) do(ctx context.Context, method, url string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func (l *linkChecker) Describe(ch chan<- *prometheus.Desc) {
+	l.checks.Describe(ch)
+}
+
+func (l *linkChecker) Collect(ch chan<- prometheus.Metric) {
+	l.checks.Collect(ch)
+}
+
+// newProxyDialer returns a dialer of the SOCKS5 proxy at addr. If addr is empty, the proxy is taken
+// from the environment.
+func newProxyDialer(addr string) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	if addr == "" {
+		return proxy.Dial, nil
+	}
+	d, err := proxy.SOCKS5("tcp", addr, nil, proxy.Direct)
+	if err != nil {
+		return nil, err
+	}
+	return d.(proxy.ContextDialer).DialContext, nil
+}
+
+func newLinkChecker(logger *zap.Logger, db *badger.DB, lock sync.Locker, cache *evtcache.Cache, cfg *config) (*linkChecker, error) {
+	dial, err := newProxyDialer(cfg.TorProxy)
+	if err != nil {
+		return nil, err
+	}
+	return &linkChecker{
+		logger: logger,
+		db:     db,
+		lock:   lock,
+		cache:  cache,
+		client: &http.Client{
+			Transport: &http.Transport{
+				DialContext:       dial,
+				DisableKeepAlives: true,
+			},
+		},
+		interval: cfg.LinkCheckInterval,
+		timeout:  cfg.LinkCheckTimeout,
+		checks: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "vworp",
+				Subsystem: "linkcheck",
+				Name:      "checks_total",
+				Help:      "Number of checked links by the result.",
+			},
+			[]string{"result"},
+		),
+	}, nil
+}
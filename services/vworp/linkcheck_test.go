@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	badger "github.com/dgraph-io/badger/v2"
+	"github.com/onionltd/mono/services/vworp/badger/links"
+	"github.com/onionltd/mono/services/vworp/badger/reachability"
+	"github.com/oniontree-org/go-oniontree/scanner"
+	"github.com/oniontree-org/go-oniontree/scanner/evtcache"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLinkChecker(t *testing.T) {
+	const serviceID = "example"
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/reachable":
+			w.WriteHeader(http.StatusOK)
+		case "/head-not-allowed":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer mirror.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache := &evtcache.Cache{}
+	eventCh := make(chan scanner.Event)
+	go cache.ReadEvents(ctx, eventCh, nil)
+	eventCh <- scanner.ScanEvent{Status: scanner.StatusOnline, URL: mirror.URL, ServiceID: serviceID}
+	// The cache has processed the first event once it receives the second one.
+	eventCh <- scanner.ScanEvent{Status: scanner.StatusOffline, URL: "http://other.onion", ServiceID: "other"}
+
+	newLink := func(path string) *links.Link {
+		link, err := links.NewLink(serviceID, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		link.SetExpiresAt(time.Now().Add(24 * time.Hour))
+		if err := links.Store(db, link); err != nil {
+			t.Fatal(err)
+		}
+		return link
+	}
+	reachable := newLink("/reachable")
+	headNotAllowed := newLink("/head-not-allowed")
+	unreachable := newLink("/unreachable")
+
+	cfg := &config{LinkCheckTimeout: 5 * time.Second}
+	checker, err := newLinkChecker(zap.NewNop(), db, &sync.Mutex{}, cache, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker.client = &http.Client{}
+
+	if err := checker.checkAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := reachability.Load(db, reachable.Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, r.Reachable())
+	assert.Equal(t, http.StatusOK, r.StatusCode())
+	assert.Equal(t, reachable.Expires().Unix(), r.Expires().Unix())
+
+	r, err = reachability.Load(db, headNotAllowed.Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, r.Reachable())
+
+	r, err = reachability.Load(db, unreachable.Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, r.Reachable())
+	assert.Equal(t, http.StatusNotFound, r.StatusCode())
+}
+
+func TestLinkCheckerOffline(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(tempDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	link, err := links.NewLink("example", "/article")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := links.Store(db, link); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is recorded, if the service has no online mirror.
+	checker, err := newLinkChecker(zap.NewNop(), db, &sync.Mutex{}, &evtcache.Cache{}, &config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checker.checkAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	_, err = reachability.Load(db, link.Fingerprint())
+	assert.Equal(t, badger.ErrKeyNotFound, err)
+}
+
+func TestNewProxyDialer(t *testing.T) {
+	dial, err := newProxyDialer("127.0.0.1:9050")
+	assert.NoError(t, err)
+	assert.NotNil(t, dial)
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dgraph-io/badger/v2"
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
+	urlutil "github.com/onionltd/mono/pkg/utils/url"
+	"github.com/onionltd/mono/services/vworp/badger/links"
+	"github.com/onionltd/mono/services/vworp/badger/reachability"
+	"github.com/onionltd/mono/services/vworp/badger/slugs"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// linkLifetimes maps values of the `expires` form field to link lifetimes.
+var linkLifetimes = map[string]time.Duration{
+	"1d": 24 * time.Hour,
+	"1w": 7 * 24 * time.Hour,
+	"1m": 30 * 24 * time.Hour,
+}
+
+// createLink parses rawLink and stores a new link in the database. The returned status code
+// is http.StatusOK on success, otherwise it describes why the link was not created.
+// A management token is returned only if the link was created by this call. Optionally,
+// the link is given a human-readable slug. Creation of links is rate limited, session
+// is optional.
+func (s *server) createLink(session, rawLink, expires, slug string) (*links.Link, string, int) {
+	parseExpiresAt := func(v string) (time.Time, bool) {
+		if v == "" || v == "never" {
+			return time.Time{}, true
+		}
+		d, ok := linkLifetimes[v]
+		if !ok {
+			return time.Time{}, false
+		}
+		return time.Now().Add(d), true
+	}
+
+	u, err := url.Parse(
+		strings.TrimSpace(rawLink),
+	)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, "", http.StatusBadRequest
+	}
+
+	expiresAt, ok := parseExpiresAt(expires)
+	if !ok {
+		return nil, "", http.StatusBadRequest
+	}
+
+	slug = slugs.Normalize(slug)
+	if slug != "" {
+		if err := slugs.Validate(slug); err != nil {
+			return nil, "", http.StatusUnprocessableEntity
+		}
+	}
+
+	// Onion addresses with a broken checksum are rejected too.
+	addr, err := urlutil.NormalizeAddress(
+		fmt.Sprintf("%s://%s", u.Scheme, u.Host),
+	)
+	if err != nil {
+		return nil, "", http.StatusBadRequest
+	}
+
+	serviceID, ok := s.addrs.GetServiceID(addr)
+	if !ok {
+		return nil, "", http.StatusNotFound
+	}
+
+	// Someone just pasted a vworp! link
+	if serviceID == "vworp" {
+		return nil, "", http.StatusNotAcceptable
+	}
+
+	path := urlToPath(u)
+	if s.blocks.Blocked(serviceID, "", path) {
+		return nil, "", http.StatusUnavailableForLegalReasons
+	}
+
+	link, err := links.NewLink(serviceID, path)
+	if err != nil {
+		s.logger.Error("failed to create a new link", zap.Error(err))
+		return nil, "", http.StatusInternalServerError
+	}
+	link.SetExpiresAt(expiresAt)
+
+	token, err := links.NewToken()
+	if err != nil {
+		s.logger.Error("failed to create a management token", zap.Error(err))
+		return nil, "", http.StatusInternalServerError
+	}
+	link.SetToken(token)
+	link.SetSlug(slug)
+
+	link, err = s.storeLink(session, link)
+	if err != nil {
+		if errors.Is(err, errRateLimited) {
+			return nil, "", http.StatusTooManyRequests
+		}
+		if errors.Is(err, errLinkBlocked) {
+			return nil, "", http.StatusUnavailableForLegalReasons
+		}
+		if errors.Is(err, slugs.ErrTaken) {
+			return nil, "", http.StatusConflict
+		}
+		if errors.Is(err, links.ErrExists) {
+			return nil, "", http.StatusForbidden
+		}
+		s.logger.Error("failed to update the database", zap.Error(err))
+		s.metrics.BadgerError("store_link")
+		return nil, "", http.StatusInternalServerError
+	}
+
+	// The link existed before, the token belongs to someone else.
+	if !link.VerifyToken(token) {
+		token = ""
+	}
+	return link, token, http.StatusOK
+}
+
+// storeLink writes the link to the database, unless the same link is already stored, in which case
+// the stored link is extended. Creation of new links is rate limited, tokens are taken within the same
+// transaction, so they're not spent if the link is not written. The stored link is returned.
+// Blocked links are not written, errLinkBlocked is returned instead.
+func (s *server) storeLink(session string, link *links.Link) (*links.Link, error) {
+	slug := link.Slug()
+	for i := 0; i < rateLimitMaxRetries; i++ {
+		// The transaction may be retried, the link is modified only once it's stored.
+		l := *link
+		err := s.badgerDB.Update(func(txn *badger.Txn) error {
+			existing, err := links.ResolveTxn(txn, &l)
+			if err != nil {
+				return err
+			}
+			// The fingerprint is known only now. A blocked link is neither extended nor restored.
+			if s.blocks.BlockedLink(&l) {
+				return errLinkBlocked
+			}
+			if existing == nil || existing.Expired() {
+				allowed, err := s.limiter.AllowTxn(txn, session, l.ServiceID())
+				if err != nil {
+					return err
+				}
+				if !allowed {
+					return errRateLimited
+				}
+			}
+
+			if err := links.StoreTxn(txn, &l); err != nil {
+				return err
+			}
+			// The result of the last check must live as long as the link.
+			if existing != nil {
+				if err := reachability.ExtendTxn(txn, &l); err != nil {
+					return err
+				}
+			}
+			// The link existed before, only its creator could have named it.
+			if slug != "" && l.Slug() != slug {
+				return links.ErrExists
+			}
+			if l.Slug() == "" {
+				return nil
+			}
+			// The slug is written again even if it's kept from before, the lifetime of the link
+			// may have been extended.
+			return slugs.StoreTxn(txn, slugs.NewSlug(l.Slug(), l.Fingerprint(), l.Expires()))
+		})
+		if errors.Is(err, badger.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &l, nil
+	}
+	return nil, badger.ErrConflict
+}
+
+// parsePath parses a path of a link, as entered by the user.
+func parsePath(rawPath string) (string, bool) {
+	u, err := url.Parse(
+		strings.TrimSpace(rawPath),
+	)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return "", false
+	}
+	if !strings.HasPrefix(u.Path, "/") {
+		return "", false
+	}
+	return urlToPath(u), true
+}
+
+// urlToPath strips scheme and host from u.
+func urlToPath(u *url.URL) string {
+	path := u.Path
+	// Force root directory, if not present.
+	if path == "" {
+		path = "/"
+	}
+	return (&url.URL{
+		Path:     path,
+		RawQuery: u.RawQuery,
+		Fragment: u.Fragment,
+	}).String()
+}
+
+// loadLink reads a link from the database, fingerprint may as well be a slug of the link.
+// The returned status code is http.StatusOK on success, otherwise it describes why the link
+// cannot be used. Blocked links can't be used at all.
+func (s *server) loadLink(fingerprint string) (*links.Link, int) {
+	if slugs.IsSlug(fingerprint) {
+		slug, err := slugs.Load(s.badgerDB, fingerprint)
+		if err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return nil, http.StatusNotFound
+			}
+			s.logger.Error("failed to read the database", zap.Error(err))
+			s.metrics.BadgerError("load_slug")
+			return nil, http.StatusInternalServerError
+		}
+		fingerprint = slug.Fingerprint()
+	}
+
+	key := links.NewKey(fingerprint)
+	link := &links.Link{}
+	if err := badgerutil.Load(s.badgerDB, key, link); err != nil {
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil, http.StatusNotFound
+		}
+		s.logger.Error("failed to read the database", zap.Error(err))
+		s.metrics.BadgerError("load_link")
+		return nil, http.StatusInternalServerError
+	}
+	if link.Expired() {
+		return nil, http.StatusGone
+	}
+	if s.blocks.BlockedLink(link) {
+		return nil, http.StatusUnavailableForLegalReasons
+	}
+	return link, http.StatusOK
+}
+
+// getOnlineMirrors returns online addresses of a service, v3 addresses first.
+func (s *server) getOnlineMirrors(serviceID string) []string {
+	online, _ := s.cache.GetOnlineAddresses(serviceID)
+	sortAddresses(online)
+	return online
+}
+
+// selectMirror returns an online address of a service users should be redirected to.
+// Empty string is returned if the service is offline.
+func (s *server) selectMirror(serviceID string) string {
+	return s.mirrors.Select(serviceID, s.getOnlineMirrors(serviceID))
+}
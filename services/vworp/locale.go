@@ -0,0 +1,62 @@
+package main
+
+import (
+	"github.com/labstack/echo/v4"
+	"golang.org/x/text/language"
+	"net/http"
+)
+
+const (
+	localeCookieName = "vworp_lang"
+	localeContextKey = "locale"
+)
+
+// selectLocale picks a language of the response. Explicit `lang` query parameter takes precedence
+// and is remembered in a cookie for the rest of the session, Accept-Language header is used otherwise.
+// Tor Browser reports the same Accept-Language to everyone, so the override is the only way for
+// most users to switch languages.
+func (s *server) selectLocale() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			prefs := make([]string, 0, 3)
+			if lang := c.QueryParam("lang"); lang != "" {
+				c.SetCookie(&http.Cookie{
+					Name:     localeCookieName,
+					Value:    s.locales.Match(lang).String(),
+					Path:     "/",
+					HttpOnly: true,
+					SameSite: http.SameSiteStrictMode,
+				})
+				prefs = append(prefs, lang)
+			}
+			if cookie, err := c.Cookie(localeCookieName); err == nil {
+				prefs = append(prefs, cookie.Value)
+			}
+			prefs = append(prefs, c.Request().Header.Get("Accept-Language"))
+
+			c.Set(localeContextKey, s.locales.Match(prefs...))
+			return next(c)
+		}
+	}
+}
+
+// localeOf returns the language selected for the request, zero tag if none was selected.
+func localeOf(c echo.Context) language.Tag {
+	if c == nil {
+		return language.Und
+	}
+	tag, _ := c.Get(localeContextKey).(language.Tag)
+	return tag
+}
+
+// languageURL returns the current URL relative to its path, with the language changed to tag.
+func languageURL(c echo.Context, tag language.Tag) string {
+	query := c.Request().URL.Query()
+	query.Set("lang", tag.String())
+	return "?" + query.Encode()
+}
+
+// translate returns the message in the language of the request.
+func (s *server) translate(c echo.Context, msg string, args ...interface{}) string {
+	return s.locales.Translate(localeOf(c), msg, args...)
+}
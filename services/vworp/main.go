@@ -12,13 +12,18 @@ import (
 	captcha "github.com/onionltd/mono/pkg/base64captcha"
 	echoerrors "github.com/onionltd/mono/pkg/echo/errors"
 	loggermw "github.com/onionltd/mono/pkg/echo/middleware/logger"
+	"github.com/onionltd/mono/pkg/hashcash"
+	"github.com/onionltd/mono/pkg/i18n"
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
 	zaputil "github.com/onionltd/mono/pkg/utils/zap"
+	"github.com/onionltd/mono/services/vworp/badger/captchas"
 	"github.com/oniontree-org/go-oniontree"
 	"github.com/oniontree-org/go-oniontree/scanner"
 	"github.com/oniontree-org/go-oniontree/scanner/evtcache"
 	"github.com/oniontree-org/go-oniontree/scanner/evtmetrics"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
+	"golang.org/x/text/language"
 	"net/http"
 	"os"
 	"os/signal"
@@ -38,18 +43,27 @@ func run() error {
 	}
 	httpdLogger := rootLogger.Named("httpd")
 	templatesLogger := rootLogger.Named("templates")
+	backupLogger := rootLogger.Named("backup")
+	badgerLogger := rootLogger.Named("badger")
+	linkCheckLogger := rootLogger.Named("linkcheck")
+	captchaLogger := rootLogger.Named("captcha")
 
 	ot, err := setupOnionTree(cfg)
 	if err != nil {
 		return err
 	}
 
-	templates, err := setupTemplates(templatesLogger, cfg)
+	locales, err := setupLocales(cfg)
 	if err != nil {
 		return err
 	}
 
-	db, err := setupBadger(cfg)
+	templates, err := setupTemplates(templatesLogger, cfg, locales)
+	if err != nil {
+		return err
+	}
+
+	db, err := setupBadger(badgerLogger, cfg)
 	if err != nil {
 		return err
 	}
@@ -58,20 +72,45 @@ func run() error {
 	scanr := setupScanner(cfg)
 	cache := setupEventCache()
 	metrics := setupEventMetrics()
+	appMetrics := setupAppMetrics()
+	failures := setupMirrorFailures()
+	addresses := setupAddressIndex()
 	router := setupRouter(httpdLogger, templates)
 
+	blocks, err := setupBlocklist(db)
+	if err != nil {
+		return err
+	}
+
 	server := server{
 		logger:   httpdLogger,
 		config:   cfg,
 		router:   router,
 		cache:    cache,
+		addrs:    addresses,
+		blocks:   blocks,
+		mirrors:  setupMirrorSelector(cfg, failures),
 		badgerDB: db,
 		ot:       ot,
 		oopsSet:  oopsies,
-		captcha:  setupCaptcha(),
+		locales:  locales,
+		captcha:  setupCaptcha(captchaLogger, cfg, db),
+		pow:      setupProofOfWork(cfg),
+		metrics:  appMetrics,
+		limiter:  setupRateLimiter(cfg, db),
 	}
 	server.routes()
 
+	backups := setupBackupScheduler(backupLogger, cfg, db, server.badgerDBLock.RLocker())
+	checker, err := setupLinkChecker(linkCheckLogger, cfg, db, server.badgerDBLock.RLocker(), cache)
+	if err != nil {
+		return err
+	}
+	maintenance := setupBadgerMaintenance(badgerLogger, cfg, db, server.badgerDBLock.RLocker())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	// Handle termination signals
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt)
@@ -79,6 +118,7 @@ func run() error {
 	go func() {
 		<-sigCh
 		rootLogger.Warn("received a termination signal")
+		cancel()
 
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -89,9 +129,11 @@ func run() error {
 
 	eventCh := make(chan scanner.Event)
 	eventCopyCh := make(chan scanner.Event)
+	eventCopyCh2 := make(chan scanner.Event)
+	eventCopyCh3 := make(chan scanner.Event)
 
 	wg := sync.WaitGroup{}
-	wg.Add(4)
+	wg.Add(6)
 
 	go func() {
 		defer wg.Done()
@@ -109,11 +151,25 @@ func run() error {
 	}()
 	go func() {
 		defer wg.Done()
-		if err := metrics.ReadEvents(context.Background(), eventCopyCh, nil); err != nil {
+		if err := metrics.ReadEvents(context.Background(), eventCopyCh, eventCopyCh2); err != nil {
 			rootLogger.Error("metrics error", zap.Error(err))
 			die()
 		}
 	}()
+	go func() {
+		defer wg.Done()
+		if err := failures.ReadEvents(context.Background(), eventCopyCh2, eventCopyCh3); err != nil {
+			rootLogger.Error("mirror failures error", zap.Error(err))
+			die()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if err := addresses.ReadEvents(context.Background(), eventCopyCh3, nil); err != nil {
+			rootLogger.Error("address index error", zap.Error(err))
+			die()
+		}
+	}()
 	go func() {
 		defer wg.Done()
 		if err := router.Start(cfg.Listen); err != nil {
@@ -124,6 +180,39 @@ func run() error {
 		}
 	}()
 
+	if backups != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := backups.Run(ctx); err != nil {
+				rootLogger.Error("backup scheduler error", zap.Error(err))
+				die()
+			}
+		}()
+	}
+
+	if maintenance != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := maintenance.Run(ctx); err != nil {
+				rootLogger.Error("badger maintenance error", zap.Error(err))
+				die()
+			}
+		}()
+	}
+
+	if checker != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := checker.Run(ctx); err != nil {
+				rootLogger.Error("link checker error", zap.Error(err))
+				die()
+			}
+		}()
+	}
+
 	wg.Wait()
 	return nil
 }
@@ -134,6 +223,9 @@ func setupConfig() (*config, error) {
 	if _, err := parser.Parse(); err != nil {
 		return nil, err
 	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
 
@@ -145,9 +237,21 @@ func setupOnionTree(cfg *config) (*oniontree.OnionTree, error) {
 	return oniontree.Open(cfg.OnionTreeDir)
 }
 
-func setupTemplates(logger *zap.Logger, cfg *config) (*Templates, error) {
+func setupLocales(cfg *config) (*i18n.Bundle, error) {
+	b := i18n.NewBundle(language.English)
+	if cfg.LocalesDir == "" {
+		return b, nil
+	}
+	if err := b.LoadDir(cfg.LocalesDir); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func setupTemplates(logger *zap.Logger, cfg *config, locales *i18n.Bundle) (*Templates, error) {
 	t := &Templates{
-		logger: logger,
+		logger:  logger,
+		locales: locales,
 	}
 	if err := t.Load(cfg.TemplatesDir); err != nil {
 		return nil, err
@@ -165,17 +269,42 @@ func setupRouter(logger *zap.Logger, t *Templates) *echo.Echo {
 
 	// Setup prometheus metrics
 	p := prometheusmw.NewPrometheus("httpd", nil)
+	// Route patterns keep submission IDs, fingerprints and slugs out of the labels.
 	p.RequestCounterURLLabelMappingFunc = func(c echo.Context) string {
-		return c.Request().RequestURI
+		return c.Path()
 	}
 	p.Use(e)
 	return e
 }
 
-func setupBadger(cfg *config) (*badger.DB, error) {
+func setupBadger(logger *zap.Logger, cfg *config) (*badger.DB, error) {
 	opts := badger.DefaultOptions(cfg.BadgerDBDir)
 	opts = opts.WithValueLogLoadingMode(options.FileIO)
-	return badger.Open(opts)
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := badgerutil.Migrate(db, migrations)
+	for _, m := range applied {
+		logger.Info("database migrated",
+			zap.Uint64("version", m.Version),
+			zap.String("description", m.Description),
+		)
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func setupBadgerMaintenance(logger *zap.Logger, cfg *config, db *badger.DB, lock sync.Locker) *badgerutil.Maintenance {
+	if cfg.BadgerGCInterval == 0 {
+		return nil
+	}
+	m := badgerutil.NewMaintenance(logger, db, lock, "vworp", cfg.BadgerGCInterval, cfg.BadgerGCDiscardRatio)
+	prometheus.MustRegister(m)
+	return m
 }
 
 func setupScanner(cfg *config) *scanner.Scanner {
@@ -190,14 +319,81 @@ func setupEventCache() *evtcache.Cache {
 	return &evtcache.Cache{}
 }
 
+func setupAppMetrics() *appMetrics {
+	m := newAppMetrics()
+	prometheus.MustRegister(m)
+	return m
+}
+
 func setupEventMetrics() *evtmetrics.Metrics {
 	m := evtmetrics.New()
 	prometheus.MustRegister(m)
 	return m
 }
 
-func setupCaptcha() *captcha.Captcha {
-	return captcha.NewCaptcha(base64Captcha.DefaultDriverDigit, base64Captcha.DefaultMemStore)
+func setupMirrorFailures() *mirrorFailures {
+	return newMirrorFailures()
+}
+
+func setupAddressIndex() *addressIndex {
+	return newAddressIndex()
+}
+
+func setupBlocklist(db *badger.DB) (*blocklist, error) {
+	b := newBlocklist()
+	if err := b.Load(db); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func setupMirrorSelector(cfg *config, failures *mirrorFailures) mirrorSelector {
+	return newMirrorSelector(cfg.MirrorStrategy, failures)
+}
+
+func setupRateLimiter(cfg *config, db *badger.DB) *rateLimiter {
+	r := newRateLimiter(db, cfg)
+	prometheus.MustRegister(r)
+	return r
+}
+
+func setupLinkChecker(logger *zap.Logger, cfg *config, db *badger.DB, lock sync.Locker, cache *evtcache.Cache) (*linkChecker, error) {
+	if cfg.LinkCheckInterval == 0 {
+		return nil, nil
+	}
+	l, err := newLinkChecker(logger, db, lock, cache, cfg)
+	if err != nil {
+		return nil, err
+	}
+	prometheus.MustRegister(l)
+	return l, nil
+}
+
+func setupBackupScheduler(logger *zap.Logger, cfg *config, db *badger.DB, lock sync.Locker) *backupScheduler {
+	if cfg.BackupDir == "" {
+		return nil
+	}
+	b := newBackupScheduler(logger, db, lock, cfg)
+	prometheus.MustRegister(b)
+	return b
+}
+
+func setupCaptcha(logger *zap.Logger, cfg *config, db *badger.DB) *captcha.Captcha {
+	var store base64Captcha.Store
+	switch cfg.CaptchaStore {
+	case "badger":
+		store = captchas.NewStore(logger, db, cfg.CaptchaTTL)
+	default:
+		store = base64Captcha.NewMemoryStore(base64Captcha.GCLimitNumber, cfg.CaptchaTTL)
+	}
+	return captcha.NewCaptcha(base64Captcha.DefaultDriverDigit, store)
+}
+
+func setupProofOfWork(cfg *config) *hashcash.Hashcash {
+	if cfg.ChallengeMode != "pow" {
+		return nil
+	}
+	return hashcash.NewHashcash(cfg.PoWDifficulty, cfg.PoWTTL)
 }
 
 func die() {
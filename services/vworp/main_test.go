@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net/http"
+	"testing"
+)
+
+// TestSetupRouterMetrics is the only test that uses setupRouter, metrics can be registered only once.
+func TestSetupRouterMetrics(t *testing.T) {
+	s := newTestServer(t)
+	s.router = setupRouter(zap.NewNop(), s.router.Renderer.(*Templates))
+	s.routes()
+	link := s.storeLink(t, "/page", "my-page")
+
+	for _, id := range []string{link.Fingerprint(), "my-page"} {
+		rec := s.get(fmt.Sprintf("/to/%s/%s?preview", testServiceID, id))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	rec := s.get("/sorry?submission=made-up-submission")
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+
+	rec = s.get("/metrics")
+	if !assert.Equal(t, http.StatusOK, rec.Code) {
+		t.FailNow()
+	}
+	metrics := rec.Body.String()
+	assert.Contains(t, metrics, `url="/to/:id/:fp"`)
+	assert.Contains(t, metrics, `url="/sorry"`)
+	assert.NotContains(t, metrics, link.Fingerprint())
+	assert.NotContains(t, metrics, "my-page")
+	assert.NotContains(t, metrics, "made-up-submission")
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"strconv"
+	"time"
+)
+
+// appMetrics counts what happens in vworp! flows. Labels must never include fingerprints, slugs or paths of links.
+type appMetrics struct {
+	linksNew     *prometheus.CounterVec
+	redirects    *prometheus.CounterVec
+	linksView    *prometheus.CounterVec
+	challenges   *prometheus.CounterVec
+	badgerErrors *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+}
+
+// LinkSubmitted counts a submission of a new link by the status code it resulted in.
+func (m *appMetrics) LinkSubmitted(code int) {
+	m.linksNew.WithLabelValues(strconv.Itoa(code)).Inc()
+}
+
+// Redirect counts a request of a link by its result: redirected, preview, offline or an oops code.
+func (m *appMetrics) Redirect(result string) {
+	m.redirects.WithLabelValues(result).Inc()
+}
+
+func (m *appMetrics) LinkViewed(section string, code int) {
+	m.linksView.WithLabelValues(section, strconv.Itoa(code)).Inc()
+}
+
+// Challenge counts challenges by the result: challenged, solved, failed, expired or error.
+func (m *appMetrics) Challenge(result string) {
+	m.challenges.WithLabelValues(result).Inc()
+}
+
+// BadgerError counts a failed database operation.
+func (m *appMetrics) BadgerError(operation string) {
+	m.badgerErrors.WithLabelValues(operation).Inc()
+}
+
+// ObserveDuration records time spent in the handler since start.
+func (m *appMetrics) ObserveDuration(handler string, start time.Time) {
+	m.duration.WithLabelValues(handler).Observe(time.Since(start).Seconds())
+}
+
+func (m *appMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.linksNew.Describe(ch)
+	m.redirects.Describe(ch)
+	m.linksView.Describe(ch)
+	m.challenges.Describe(ch)
+	m.badgerErrors.Describe(ch)
+	m.duration.Describe(ch)
+}
+
+func (m *appMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.linksNew.Collect(ch)
+	m.redirects.Collect(ch)
+	m.linksView.Collect(ch)
+	m.challenges.Collect(ch)
+	m.badgerErrors.Collect(ch)
+	m.duration.Collect(ch)
+}
+
+func newAppMetrics() *appMetrics {
+	return &appMetrics{
+		linksNew: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "vworp",
+				Subsystem: "links",
+				Name:      "submitted_total",
+				Help:      "Number of submitted links by the resulting status code.",
+			},
+			[]string{"code"},
+		),
+		redirects: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "vworp",
+				Subsystem: "links",
+				Name:      "redirects_total",
+				Help:      "Number of requested links by the result.",
+			},
+			[]string{"result"},
+		),
+		linksView: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "vworp",
+				Subsystem: "links",
+				Name:      "views_total",
+				Help:      "Number of views of link pages by the section and the resulting status code.",
+			},
+			[]string{"section", "code"},
+		),
+		challenges: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "vworp",
+				Subsystem: "challenge",
+				Name:      "results_total",
+				Help:      "Number of challenges by the result.",
+			},
+			[]string{"result"},
+		),
+		badgerErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "vworp",
+				Subsystem: "badger",
+				Name:      "errors_total",
+				Help:      "Number of failed database operations.",
+			},
+			[]string{"operation"},
+		),
+		duration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: "vworp",
+				Subsystem: "handler",
+				Name:      "duration_seconds",
+				Help:      "Time spent handling requests.",
+				Buckets:   prometheus.DefBuckets,
+			},
+			[]string{"handler"},
+		),
+	}
+}
@@ -1,64 +1,178 @@
 package main
 
 import (
+	"errors"
+	"github.com/dgraph-io/badger/v2"
 	"github.com/labstack/echo/v4"
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
+	"github.com/onionltd/mono/services/vworp/badger/submissions"
+	"go.uber.org/zap"
 	"net/http"
+	"net/url"
+	"time"
 )
 
-func (s *server) solveCaptcha() echo.MiddlewareFunc {
+// lockBadgerDB prevents requests from accessing the database while a backup is being restored.
+func (s *server) lockBadgerDB() echo.MiddlewareFunc {
+	isRestore := func(c echo.Context) bool {
+		return c.Request().Method == http.MethodPost && c.Path() == "/backup/badgerdb"
+	}
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
-		checkSolution := func(id, solution string) bool {
-			return s.captcha.Verify(id, solution)
+		return func(c echo.Context) error {
+			if isRestore(c) {
+				return next(c)
+			}
+			s.badgerDBLock.RLock()
+			defer s.badgerDBLock.RUnlock()
+			return next(c)
 		}
-		solveRedirect := func(c echo.Context) error {
-			formValues, err := c.FormParams()
+	}
+}
+
+// startSession gives clients without a session cookie a new session, before they submit a form.
+func (s *server) startSession() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if cookie, err := c.Cookie(sessionCookieName); err == nil && cookie.Value != "" {
+				return next(c)
+			}
+			id, err := newSessionID()
 			if err != nil {
-				// TODO: redirect to /sorry/oops?
-				return c.Redirect(http.StatusSeeOther, "/")
+				s.logger.Error("failed to create a session", zap.Error(err))
+				return next(c)
 			}
+			c.SetCookie(&http.Cookie{
+				Name:     sessionCookieName,
+				Value:    id,
+				Path:     "/",
+				HttpOnly: true,
+				SameSite: http.SameSiteStrictMode,
+			})
+			return next(c)
+		}
+	}
+}
 
+func (s *server) solveCaptcha() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		checkSolution := func(c echo.Context, sub *submissions.Submission) bool {
+			// Clients without JavaScript fall back to the captcha.
+			if s.pow != nil && c.FormValue("pow_solution") != "" {
+				return s.pow.Verify(sub.Values().Get("pow"), c.FormValue("pow_solution"))
+			}
+			return s.captcha.Verify(sub.Values().Get("cid"), c.FormValue("solution"))
+		}
+		// newChallenge sets IDs of new challenges to formValues.
+		newChallenge := func(formValues url.Values) error {
 			captchaID, err := s.captcha.Generate()
 			if err != nil {
+				return err
+			}
+			formValues.Set("cid", captchaID)
+
+			if s.pow != nil {
+				nonce, err := s.pow.Generate()
+				if err != nil {
+					return err
+				}
+				formValues.Set("pow", nonce)
+			}
+			return nil
+		}
+		// challengeRedirect keeps formValues on the server and redirects the user to solve a new challenge.
+		// Only ID of the submission is sent to the user.
+		challengeRedirect := func(c echo.Context, formValues url.Values) error {
+			if err := newChallenge(formValues); err != nil {
+				s.metrics.Challenge("error")
 				// TODO: redirect to /sorry/oops?
 				return c.Redirect(http.StatusSeeOther, "/")
 			}
 
-			formValues.Set("cid", captchaID)
-			formValues.Set("continue", c.Request().RequestURI)
-			// Remove captcha specific fields to prevent duplication.
-			formValues.Del("solution")
-			return c.Redirect(http.StatusSeeOther, "/sorry?"+formValues.Encode())
-		}
-		wrongSolutionRedirect := func(c echo.Context) error {
-			formValues, err := c.FormParams()
+			sub, err := submissions.NewSubmission(formValues, time.Now().Add(s.config.CaptchaTTL))
 			if err != nil {
+				s.metrics.Challenge("error")
+				// TODO: redirect to /sorry/oops?
+				return c.Redirect(http.StatusSeeOther, "/")
+			}
+			if err := badgerutil.Store(s.badgerDB, sub); err != nil {
+				s.logger.Error("failed to store submission", zap.Error(err))
+				s.metrics.BadgerError("store_submission")
+				s.metrics.Challenge("error")
 				// TODO: redirect to /sorry/oops?
 				return c.Redirect(http.StatusSeeOther, "/")
 			}
 
-			captchaID, err := s.captcha.Generate()
+			v := url.Values{}
+			v.Set("submission", sub.ID())
+			return c.Redirect(http.StatusSeeOther, "/sorry?"+v.Encode())
+		}
+		solveRedirect := func(c echo.Context) error {
+			formValues, err := c.FormParams()
 			if err != nil {
 				// TODO: redirect to /sorry/oops?
 				return c.Redirect(http.StatusSeeOther, "/")
 			}
 
-			formValues.Set("cid", captchaID)
+			// Copy the values, so the request is not modified.
+			values := url.Values{}
+			for k, v := range formValues {
+				values[k] = v
+			}
+			values.Set("continue", c.Request().URL.Path)
 			// Remove captcha specific fields to prevent duplication.
-			formValues.Del("solution")
-			return c.Redirect(http.StatusSeeOther, "/sorry?"+formValues.Encode())
+			values.Del("solution")
+			values.Del("pow_solution")
+			values.Del("submission")
+			return challengeRedirect(c, values)
+		}
+		wrongSolutionRedirect := func(c echo.Context, sub *submissions.Submission) error {
+			return challengeRedirect(c, sub.Values())
+		}
+		// restoreForm replaces form values of the request with the values of the submission.
+		restoreForm := func(c echo.Context, sub *submissions.Submission) {
+			values := sub.Values()
+			values.Del("cid")
+			values.Del("pow")
+			values.Del("continue")
+			c.Request().Form = values
+			c.Request().PostForm = values
 		}
 		return func(c echo.Context) error {
-			continueURL := c.FormValue("continue")
-			if continueURL == "" {
+			start := time.Now()
+			// Time spent in the next handler is measured separately.
+			observe := func() {
+				s.metrics.ObserveDuration("solve_captcha", start)
+			}
+
+			id := c.FormValue("submission")
+			if id == "" {
+				defer observe()
+				s.metrics.Challenge("challenged")
 				return solveRedirect(c)
 			}
 
-			id := c.FormValue("cid")
-			solution := c.FormValue("solution")
-			if !checkSolution(id, solution) {
-				return wrongSolutionRedirect(c)
+			// Submission is removed right away, so the same solution can't be submitted twice.
+			sub, err := submissions.Take(s.badgerDB, id)
+			if err != nil {
+				defer observe()
+				if !errors.Is(err, badger.ErrKeyNotFound) {
+					s.logger.Error("failed to load submission", zap.Error(err))
+					s.metrics.BadgerError("load_submission")
+				}
+				s.metrics.Challenge("expired")
+				// The submission has expired, the user has to start over.
+				return c.Redirect(http.StatusSeeOther, "/")
+			}
+
+			if !checkSolution(c, sub) {
+				defer observe()
+				s.metrics.Challenge("failed")
+				return wrongSolutionRedirect(c, sub)
 			}
+			s.metrics.Challenge("solved")
 
+			restoreForm(c, sub)
+			observe()
 			return next(c)
 		}
 	}
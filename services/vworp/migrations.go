@@ -0,0 +1,15 @@
+package main
+
+import (
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
+	"github.com/onionltd/mono/services/vworp/badger/links"
+)
+
+// migrations upgrade the database to the current schema. New migrations are appended to the end.
+var migrations = []badgerutil.Migration{
+	{
+		Version:     1,
+		Description: "index links by service",
+		Migrate:     links.IndexAll,
+	},
+}
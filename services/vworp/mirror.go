@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"github.com/oniontree-org/go-oniontree/scanner"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	mirrorStrategyFirst               = "first"
+	mirrorStrategyRandom              = "random"
+	mirrorStrategyRoundRobin          = "round-robin"
+	mirrorStrategyLeastRecentlyFailed = "least-recently-failed"
+)
+
+// mirrorSelector picks an address the user is redirected to.
+type mirrorSelector interface {
+	// Select returns one of online addresses of the service. The addresses are sorted, v3 addresses first.
+	Select(serviceID string, online []string) string
+}
+
+type firstMirror struct{}
+
+func (firstMirror) Select(serviceID string, online []string) string {
+	if len(online) == 0 {
+		return ""
+	}
+	return online[0]
+}
+
+type randomMirror struct {
+	sync.Mutex
+	rnd *rand.Rand
+}
+
+func (m *randomMirror) Select(serviceID string, online []string) string {
+	if len(online) == 0 {
+		return ""
+	}
+	m.Lock()
+	defer m.Unlock()
+	return online[m.rnd.Intn(len(online))]
+}
+
+type roundRobinMirror struct {
+	sync.Mutex
+	// Format: next[serviceID] = index
+	next map[string]int
+}
+
+func (m *roundRobinMirror) Select(serviceID string, online []string) string {
+	if len(online) == 0 {
+		return ""
+	}
+	m.Lock()
+	defer m.Unlock()
+	idx := m.next[serviceID] % len(online)
+	m.next[serviceID] = idx + 1
+	return online[idx]
+}
+
+type leastRecentlyFailedMirror struct {
+	failures *mirrorFailures
+}
+
+func (m *leastRecentlyFailedMirror) Select(serviceID string, online []string) string {
+	if len(online) == 0 {
+		return ""
+	}
+	selected := online[0]
+	selectedFailure := m.failures.LastFailure(selected)
+	for _, addr := range online[1:] {
+		if failure := m.failures.LastFailure(addr); failure.Before(selectedFailure) {
+			selected, selectedFailure = addr, failure
+		}
+	}
+	return selected
+}
+
+func newMirrorSelector(strategy string, failures *mirrorFailures) mirrorSelector {
+	switch strategy {
+	case mirrorStrategyRandom:
+		return &randomMirror{
+			rnd: rand.New(rand.NewSource(time.Now().UnixNano())),
+		}
+	case mirrorStrategyRoundRobin:
+		return &roundRobinMirror{
+			next: make(map[string]int),
+		}
+	case mirrorStrategyLeastRecentlyFailed:
+		return &leastRecentlyFailedMirror{
+			failures: failures,
+		}
+	}
+	return firstMirror{}
+}
+
+// mirrorFailures remembers when addresses were last seen offline.
+type mirrorFailures struct {
+	sync.RWMutex
+	// Format: lastFailure[address] = time
+	lastFailure map[string]time.Time
+}
+
+func (f *mirrorFailures) ReadEvents(ctx context.Context, inputCh <-chan scanner.Event, outputCh chan<- scanner.Event) error {
+	defer func() {
+		if outputCh != nil {
+			close(outputCh)
+		}
+	}()
+
+	for {
+		select {
+		case event, more := <-inputCh:
+			if !more {
+				return nil
+			}
+
+			switch e := event.(type) {
+			case scanner.ScanEvent:
+				if e.Status == scanner.StatusOffline {
+					f.Lock()
+					f.lastFailure[e.URL] = time.Now()
+					f.Unlock()
+				}
+
+			case scanner.WorkerStopped:
+				f.Lock()
+				delete(f.lastFailure, e.URL)
+				f.Unlock()
+			}
+
+			if outputCh != nil {
+				outputCh <- event
+			}
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// LastFailure returns time when the address was last seen offline. Zero time means never.
+func (f *mirrorFailures) LastFailure(address string) time.Time {
+	f.RLock()
+	defer f.RUnlock()
+	return f.lastFailure[address]
+}
+
+func newMirrorFailures() *mirrorFailures {
+	return &mirrorFailures{
+		lastFailure: make(map[string]time.Time),
+	}
+}
+
+// sortAddresses sorts addresses, v3 addresses first.
+func sortAddresses(addrs []string) {
+	sort.Slice(addrs, func(i, j int) bool {
+		if len(addrs[i]) != len(addrs[j]) {
+			return len(addrs[i]) > len(addrs[j])
+		}
+		return addrs[i] < addrs[j]
+	})
+}
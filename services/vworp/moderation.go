@@ -0,0 +1,250 @@
+package main
+
+import (
+	"errors"
+	"github.com/dgraph-io/badger/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/onionltd/mono/services/vworp/badger/links"
+	"github.com/onionltd/mono/services/vworp/badger/moderation"
+	"go.uber.org/zap"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// adminModerationEventsLimit limits how many events of the audit trail are shown.
+const adminModerationEventsLimit = 100
+
+var errLinkBlocked = errors.New("link is blocked")
+
+// blocklist keeps blocks in memory, so that they can be checked on every redirect.
+// It has to be reloaded whenever blocks in the database change.
+type blocklist struct {
+	mu           sync.RWMutex
+	fingerprints map[string]struct{}
+	services     map[string]struct{}
+	paths        []*regexp.Regexp
+}
+
+// Load replaces blocks in memory with blocks from the database.
+func (b *blocklist) Load(db *badger.DB) error {
+	blocks, err := moderation.ListBlocks(db)
+	if err != nil {
+		return err
+	}
+
+	fingerprints := make(map[string]struct{})
+	services := make(map[string]struct{})
+	paths := make([]*regexp.Regexp, 0)
+	for _, block := range blocks {
+		switch block.Kind() {
+		case moderation.KindFingerprint:
+			fingerprints[block.Pattern()] = struct{}{}
+		case moderation.KindService:
+			services[block.Pattern()] = struct{}{}
+		case moderation.KindPath:
+			re, err := regexp.Compile(block.Pattern())
+			if err != nil {
+				return err
+			}
+			paths = append(paths, re)
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.fingerprints = fingerprints
+	b.services = services
+	b.paths = paths
+	return nil
+}
+
+// Blocked returns true if any of the blocks matches. Empty path is not matched against path blocks.
+func (b *blocklist) Blocked(serviceID, fingerprint, path string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if _, ok := b.services[serviceID]; ok {
+		return true
+	}
+	if _, ok := b.fingerprints[fingerprint]; ok {
+		return true
+	}
+	if path == "" {
+		return false
+	}
+	for _, re := range b.paths {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// BlockedLink returns true if the link has been disabled.
+func (b *blocklist) BlockedLink(link *links.Link) bool {
+	return b.Blocked(link.ServiceID(), link.Fingerprint(), link.Path())
+}
+
+func newBlocklist() *blocklist {
+	return &blocklist{
+		fingerprints: make(map[string]struct{}),
+		services:     make(map[string]struct{}),
+	}
+}
+
+type apiBlock struct {
+	ID        string          `json:"id"`
+	Kind      moderation.Kind `json:"kind"`
+	Pattern   string          `json:"pattern"`
+	Reason    string          `json:"reason,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+func newAPIBlock(b *moderation.Block) apiBlock {
+	return apiBlock{
+		ID:        b.ID(),
+		Kind:      b.Kind(),
+		Pattern:   b.Pattern(),
+		Reason:    b.Reason(),
+		CreatedAt: b.CreatedAt(),
+	}
+}
+
+type apiModerationEvent struct {
+	Action    moderation.Action `json:"action"`
+	BlockID   string            `json:"block_id"`
+	Kind      moderation.Kind   `json:"kind"`
+	Pattern   string            `json:"pattern"`
+	Reason    string            `json:"reason,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+func newAPIModerationEvent(e *moderation.Event) apiModerationEvent {
+	return apiModerationEvent{
+		Action:    e.Action(),
+		BlockID:   e.BlockID(),
+		Kind:      e.Kind(),
+		Pattern:   e.Pattern(),
+		Reason:    e.Reason(),
+		CreatedAt: e.CreatedAt(),
+	}
+}
+
+// listModeration returns all blocks and the most recent events of the audit trail.
+func (s *server) listModeration() ([]*moderation.Block, []*moderation.Event, int) {
+	blocks, err := moderation.ListBlocks(s.badgerDB)
+	if err != nil {
+		s.logger.Error("failed to list blocks", zap.Error(err))
+		return nil, nil, http.StatusInternalServerError
+	}
+	events, err := moderation.ListEvents(s.badgerDB, adminModerationEventsLimit)
+	if err != nil {
+		s.logger.Error("failed to list moderation events", zap.Error(err))
+		return nil, nil, http.StatusInternalServerError
+	}
+	return blocks, events, http.StatusOK
+}
+
+func (s *server) handleAdminModeration() echo.HandlerFunc {
+	type pageData struct {
+		Blocks []*moderation.Block
+		Events []*moderation.Event
+	}
+	return func(c echo.Context) error {
+		blocks, events, code := s.listModeration()
+		if code != http.StatusOK {
+			return c.String(code, http.StatusText(code))
+		}
+
+		pageContent := pageData{}
+		pageContent.Blocks = blocks
+		pageContent.Events = events
+		return c.Render(http.StatusOK, "admin_moderation", pageContent)
+	}
+}
+
+func (s *server) handleAdminModerationJSON() echo.HandlerFunc {
+	type response struct {
+		Blocks []apiBlock           `json:"blocks"`
+		Events []apiModerationEvent `json:"events"`
+	}
+	return func(c echo.Context) error {
+		blocks, events, code := s.listModeration()
+		if code != http.StatusOK {
+			return apiError(c, code)
+		}
+
+		resp := response{
+			Blocks: make([]apiBlock, 0, len(blocks)),
+			Events: make([]apiModerationEvent, 0, len(events)),
+		}
+		for _, b := range blocks {
+			resp.Blocks = append(resp.Blocks, newAPIBlock(b))
+		}
+		for _, e := range events {
+			resp.Events = append(resp.Events, newAPIModerationEvent(e))
+		}
+		return c.JSON(http.StatusOK, resp)
+	}
+}
+
+func (s *server) handleAdminModerationBlock() echo.HandlerFunc {
+	type request struct {
+		Kind    string `json:"kind" form:"kind"`
+		Pattern string `json:"pattern" form:"pattern"`
+		Reason  string `json:"reason" form:"reason"`
+	}
+	return func(c echo.Context) error {
+		req := request{}
+		if err := c.Bind(&req); err != nil {
+			return apiError(c, http.StatusBadRequest)
+		}
+
+		block, err := moderation.NewBlock(moderation.Kind(req.Kind), req.Pattern, req.Reason)
+		if err != nil {
+			return apiError(c, http.StatusBadRequest)
+		}
+
+		if err := moderation.Add(s.badgerDB, block); err != nil {
+			s.logger.Error("failed to store a block", zap.Error(err))
+			return apiError(c, http.StatusInternalServerError)
+		}
+		if err := s.blocks.Load(s.badgerDB); err != nil {
+			s.logger.Error("failed to reload blocks", zap.Error(err))
+			return apiError(c, http.StatusInternalServerError)
+		}
+
+		s.logger.Info("block added",
+			zap.String("id", block.ID()),
+			zap.String("kind", string(block.Kind())),
+			zap.String("pattern", block.Pattern()),
+		)
+		return c.JSON(http.StatusCreated, newAPIBlock(block))
+	}
+}
+
+func (s *server) handleAdminModerationUnblock() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		block, err := moderation.Remove(s.badgerDB, c.Param("id"), c.FormValue("reason"))
+		if err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return apiError(c, http.StatusNotFound)
+			}
+			s.logger.Error("failed to remove a block", zap.Error(err))
+			return apiError(c, http.StatusInternalServerError)
+		}
+		if err := s.blocks.Load(s.badgerDB); err != nil {
+			s.logger.Error("failed to reload blocks", zap.Error(err))
+			return apiError(c, http.StatusInternalServerError)
+		}
+
+		s.logger.Info("block removed",
+			zap.String("id", block.ID()),
+			zap.String("kind", string(block.Kind())),
+			zap.String("pattern", block.Pattern()),
+		)
+		return c.JSON(http.StatusOK, newAPIBlock(block))
+	}
+}
@@ -16,26 +16,67 @@ func (m oopsMessages) Get(id int) string {
 
 var oopsies = oopsSet{
 	"/links/oops/:id": {
-		0:                              "Hey! You made that up!",
-		69:                             "Haha, funny.",
-		1337:                           "Look at you, hacker. A pathetic creature of meat and bone. Panting and sweating as you run through my corridors. How can you challenge a perfect immortal machine?",
-		http.StatusBadRequest:          "This doesn't look like a valid link.",
-		http.StatusNotFound:            "Your link does not belong to any service vworp! can recognize.",
-		http.StatusNotAcceptable:       "Haha, so meta.",
-		http.StatusInternalServerError: "Hmm... Something has broken down but don't worry it's not your fault.",
+		0:                                     "Hey! You made that up!",
+		69:                                    "Haha, funny.",
+		1337:                                  "Look at you, hacker. A pathetic creature of meat and bone. Panting and sweating as you run through my corridors. How can you challenge a perfect immortal machine?",
+		http.StatusBadRequest:                 "This doesn't look like a valid link.",
+		http.StatusForbidden:                  "That link already exists under another name or none. Submit it without a name to get its address.",
+		http.StatusNotFound:                   "Your link does not belong to any service vworp! can recognize.",
+		http.StatusNotAcceptable:              "Haha, so meta.",
+		http.StatusConflict:                   "That name is already taken.",
+		http.StatusUnprocessableEntity:        "That name can't be used. Use 3 to 64 lowercase letters, digits and dashes.",
+		http.StatusTooManyRequests:            "Whoa, slow down! Too many links have been created, try again later.",
+		http.StatusUnavailableForLegalReasons: "Links to this destination are not allowed.",
+		http.StatusInternalServerError:        "Hmm... Something has broken down but don't worry it's not your fault.",
 	},
 	"/links/:fp": {
-		0:                              "Hey! You made that up!",
-		69:                             "Haha, funny.",
-		1337:                           "Look at you, hacker. A pathetic creature of meat and bone. Panting and sweating as you run through my corridors. How can you challenge a perfect immortal machine?",
-		http.StatusNotFound:            "I can't find that link.",
-		http.StatusInternalServerError: "Hmm... Something has broken down but don't worry it's not your fault.",
+		0:                                     "Hey! You made that up!",
+		69:                                    "Haha, funny.",
+		1337:                                  "Look at you, hacker. A pathetic creature of meat and bone. Panting and sweating as you run through my corridors. How can you challenge a perfect immortal machine?",
+		http.StatusNotFound:                   "I can't find that link.",
+		http.StatusGone:                       "This link has expired.",
+		http.StatusUnavailableForLegalReasons: "This link was disabled.",
+		http.StatusInternalServerError:        "Hmm... Something has broken down but don't worry it's not your fault.",
+	},
+	"/links/:fp/manage": {
+		0:                                     "Hey! You made that up!",
+		69:                                    "Haha, funny.",
+		1337:                                  "Look at you, hacker. A pathetic creature of meat and bone. Panting and sweating as you run through my corridors. How can you challenge a perfect immortal machine?",
+		http.StatusBadRequest:                 "This doesn't look like a valid path.",
+		http.StatusUnauthorized:               "That's not the right management token.",
+		http.StatusForbidden:                  "This link can't be managed.",
+		http.StatusNotFound:                   "I can't find that link.",
+		http.StatusConflict:                   "A link to that path already exists.",
+		http.StatusGone:                       "This link has expired.",
+		http.StatusUnavailableForLegalReasons: "Links to this destination are not allowed.",
+		http.StatusInternalServerError:        "Hmm... Something has broken down but don't worry it's not your fault.",
+	},
+	"/links/:fp/report": {
+		0:                                     "Hey! You made that up!",
+		69:                                    "Haha, funny.",
+		1337:                                  "Look at you, hacker. A pathetic creature of meat and bone. Panting and sweating as you run through my corridors. How can you challenge a perfect immortal machine?",
+		http.StatusBadRequest:                 "Pick a reason for the report.",
+		http.StatusNotFound:                   "I can't find that link.",
+		http.StatusGone:                       "This link has expired.",
+		http.StatusRequestEntityTooLarge:      "That's a lot of details. Keep it under 1000 characters, please.",
+		http.StatusUnavailableForLegalReasons: "This link was disabled.",
+		http.StatusInternalServerError:        "Hmm... Something has broken down but don't worry it's not your fault.",
+	},
+	"/to/:id": {
+		0:                                     "Hey! You made that up!",
+		69:                                    "Haha, funny.",
+		1337:                                  "Look at you, hacker. A pathetic creature of meat and bone. Panting and sweating as you run through my corridors. How can you challenge a perfect immortal machine?",
+		http.StatusNotFound:                   "I can't find that service.",
+		http.StatusUnavailableForLegalReasons: "This service was disabled.",
+		http.StatusInternalServerError:        "Hmm... Something has broken down but don't worry it's not your fault.",
 	},
 	"/to/:id/:fp": {
-		0:                              "Hey! You made that up!",
-		69:                             "Haha, funny.",
-		1337:                           "Look at you, hacker. A pathetic creature of meat and bone. Panting and sweating as you run through my corridors. How can you challenge a perfect immortal machine?",
-		http.StatusNotFound:            "I can't find that link.",
-		http.StatusInternalServerError: "Hmm... Something has broken down but don't worry it's not your fault.",
+		0:                                     "Hey! You made that up!",
+		69:                                    "Haha, funny.",
+		1337:                                  "Look at you, hacker. A pathetic creature of meat and bone. Panting and sweating as you run through my corridors. How can you challenge a perfect immortal machine?",
+		http.StatusNotFound:                   "I can't find that link.",
+		http.StatusGone:                       "This link has expired.",
+		http.StatusUnavailableForLegalReasons: "This link was disabled.",
+		http.StatusInternalServerError:        "Hmm... Something has broken down but don't worry it's not your fault.",
 	},
 }
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/skip2/go-qrcode"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	qrSmallSize  = 128
+	qrMediumSize = 256
+	qrLargeSize  = 512
+
+	// qrMaxAge caps how long clients may cache a QR code.
+	qrMaxAge = 24 * time.Hour
+)
+
+// qrFormat is an output format of a QR code.
+type qrFormat int
+
+const (
+	qrPNG qrFormat = iota
+	qrSVG
+)
+
+// writeSVG renders the QR code as an SVG image with one path covering all dark modules.
+func writeSVG(qr *qrcode.QRCode, size int) []byte {
+	bitmap := qr.Bitmap()
+	buf := bytes.NewBuffer([]byte{})
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, len(bitmap), len(bitmap))
+	buf.WriteString(`<rect width="100%" height="100%" fill="#fff"/><path fill="#000" d="`)
+	for y, row := range bitmap {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			// Merge adjacent dark modules into a single horizontal run.
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(buf, "M%d %dh%dv1h-%dz", start, y, x-start, x-start)
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
+
+func (s *server) handleLinksQRCode(format qrFormat) echo.HandlerFunc {
+	getQRSize := func(c echo.Context) (int, bool) {
+		switch c.QueryParam("size") {
+		case "small":
+			return qrSmallSize, true
+		case "", "medium":
+			return qrMediumSize, true
+		case "large":
+			return qrLargeSize, true
+		default:
+			return 0, false
+		}
+	}
+	// maxAge returns for how long the QR code may be cached, it's never longer than the link lives.
+	maxAge := func(expiresAt time.Time) time.Duration {
+		if expiresAt.IsZero() {
+			return qrMaxAge
+		}
+		ttl := time.Until(expiresAt)
+		if ttl > qrMaxAge {
+			return qrMaxAge
+		}
+		// The link may have expired since it was loaded.
+		if ttl < 0 {
+			return 0
+		}
+		return ttl
+	}
+	return func(c echo.Context) error {
+		link, code := s.loadLink(c.Param("fp"))
+		if code != http.StatusOK {
+			return c.String(code, http.StatusText(code))
+		}
+
+		size, ok := getQRSize(c)
+		if !ok {
+			return c.String(http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		}
+		text := fmt.Sprintf("http://%s/to/%s/%s?preview", c.Request().Host, link.ServiceID(), link.Fingerprint())
+
+		// The image depends only on the link and the requested size.
+		etag := fmt.Sprintf(`"%s-%d-%d"`, link.Fingerprint(), size, format)
+		c.Response().Header().Set("ETag", etag)
+		c.Response().Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(maxAge(link.ExpiresAt()).Seconds())))
+		c.Response().Header().Set("Vary", "Host")
+		if c.Request().Header.Get("If-None-Match") == etag {
+			return c.NoContent(http.StatusNotModified)
+		}
+
+		qr, err := qrcode.New(text, qrcode.Medium)
+		if err != nil {
+			s.logger.Error("failed to generate QR code", zap.Error(err))
+			return c.String(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		}
+
+		if format == qrSVG {
+			return c.Blob(http.StatusOK, "image/svg+xml", writeSVG(qr, size))
+		}
+		data, err := qr.PNG(size)
+		if err != nil {
+			s.logger.Error("failed to encode PNG image", zap.Error(err))
+			return c.String(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		}
+		return c.Blob(http.StatusOK, "image/png", data)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/skip2/go-qrcode"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestWriteSVG(t *testing.T) {
+	qr, err := qrcode.New("http://localhost/to/example/abcde?preview", qrcode.Medium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bitmap := qr.Bitmap()
+
+	svg := struct {
+		Width   string `xml:"width,attr"`
+		Height  string `xml:"height,attr"`
+		ViewBox string `xml:"viewBox,attr"`
+		Path    struct {
+			D string `xml:"d,attr"`
+		} `xml:"path"`
+	}{}
+	if err := xml.Unmarshal(writeSVG(qr, qrMediumSize), &svg); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "256", svg.Width)
+	assert.Equal(t, "256", svg.Height)
+	assert.Equal(t, fmt.Sprintf("0 0 %d %d", len(bitmap), len(bitmap)), svg.ViewBox)
+
+	// Runs of the path must cover exactly the dark modules.
+	dark := make(map[[2]int]bool)
+	for _, run := range strings.Split(strings.TrimSuffix(svg.Path.D, "z"), "z") {
+		x, y, w, back := 0, 0, 0, 0
+		if _, err := fmt.Sscanf(run, "M%d %dh%dv1h-%d", &x, &y, &w, &back); err != nil {
+			t.Fatalf("invalid run %q: %s", run, err)
+		}
+		assert.Equal(t, w, back)
+		for i := x; i < x+w; i++ {
+			dark[[2]int{i, y}] = true
+		}
+	}
+	for y, row := range bitmap {
+		for x, v := range row {
+			assert.Equal(t, v, dark[[2]int{x, y}], "module %d,%d", x, y)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/dgraph-io/badger/v2"
+	"github.com/onionltd/mono/services/vworp/badger/ratelimits"
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+const (
+	sessionCookieName = "vworp_session"
+	// anonymousSession is shared by all clients that didn't send back the session cookie they were given.
+	// Otherwise, a client could escape the session limit by dropping the cookie.
+	anonymousSession = "anonymous"
+
+	rateLimitGlobal  = "global"
+	rateLimitSession = "session"
+	rateLimitService = "service"
+
+	// rateLimitMaxRetries limits how many times a transaction taking tokens is retried on conflict.
+	rateLimitMaxRetries = 5
+)
+
+var errRateLimited = errors.New("rate limited")
+
+// rateLimiter throttles creation of new links. Since all clients arrive via Tor, limits are applied
+// globally, per session and per target service. The state is kept in the database, so it survives restarts.
+type rateLimiter struct {
+	db *badger.DB
+
+	globalLimit   int
+	globalPeriod  time.Duration
+	sessionLimit  int
+	sessionPeriod time.Duration
+	serviceQuota  int
+	serviceWindow time.Duration
+
+	rejected *prometheus.CounterVec
+}
+
+// AllowTxn reports whether a new link to serviceID can be created. Session is optional. Tokens are taken
+// within the transaction, so they're spent only if the transaction, which writes the link, is committed.
+func (r *rateLimiter) AllowTxn(txn *badger.Txn, session, serviceID string) (bool, error) {
+	now := time.Now()
+	if r.globalLimit > 0 {
+		ok, err := ratelimits.TakeTxn(txn, rateLimitGlobal, r.globalLimit, r.globalPeriod, now)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			r.rejected.WithLabelValues(rateLimitGlobal).Inc()
+			return false, nil
+		}
+	}
+	if r.sessionLimit > 0 && session != "" {
+		name := rateLimitSession + "." + hashSession(session)
+		ok, err := ratelimits.TakeTxn(txn, name, r.sessionLimit, r.sessionPeriod, now)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			r.rejected.WithLabelValues(rateLimitSession).Inc()
+			return false, nil
+		}
+	}
+	if r.serviceQuota > 0 {
+		name := rateLimitService + "." + serviceID
+		ok, err := ratelimits.IncrementTxn(txn, name, r.serviceQuota, r.serviceWindow, now)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			r.rejected.WithLabelValues(rateLimitService).Inc()
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (r *rateLimiter) Describe(ch chan<- *prometheus.Desc) {
+	r.rejected.Describe(ch)
+}
+
+func (r *rateLimiter) Collect(ch chan<- prometheus.Metric) {
+	r.rejected.Collect(ch)
+}
+
+func newRateLimiter(db *badger.DB, cfg *config) *rateLimiter {
+	return &rateLimiter{
+		db:            db,
+		globalLimit:   cfg.RateLimitGlobal,
+		globalPeriod:  time.Minute,
+		sessionLimit:  cfg.RateLimitSession,
+		sessionPeriod: time.Hour,
+		serviceQuota:  cfg.RateLimitService,
+		serviceWindow: 24 * time.Hour,
+		rejected: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "vworp",
+				Subsystem: "ratelimit",
+				Name:      "rejected_total",
+				Help:      "Number of link submissions rejected by a rate limit.",
+			},
+			[]string{"limit"},
+		),
+	}
+}
+
+// newSessionID returns a random session ID.
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashSession prevents session IDs from being stored in the database.
+func hashSession(session string) string {
+	sum := sha256.Sum256([]byte(session))
+	return hex.EncodeToString(sum[:])
+}
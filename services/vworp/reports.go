@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
+	"github.com/onionltd/mono/services/vworp/badger/reports"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+type apiReport struct {
+	ID        string           `json:"id"`
+	Category  reports.Category `json:"category"`
+	Comment   string           `json:"comment,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+type apiReportSummary struct {
+	Fingerprint    string                   `json:"fingerprint"`
+	ServiceID      string                   `json:"service_id"`
+	Count          int                      `json:"count"`
+	Categories     map[reports.Category]int `json:"categories"`
+	LastReportedAt time.Time                `json:"last_reported_at"`
+}
+
+func (s *server) handleLinksReport() echo.HandlerFunc {
+	oops := func(c echo.Context, code int, showSubmitForm bool) error {
+		return s.handleOops(&code, false)(c)
+	}
+	return func(c echo.Context) error {
+		link, code := s.loadLink(c.Param("fp"))
+		if code != http.StatusOK {
+			return oops(c, code, false)
+		}
+
+		report, err := reports.NewReport(
+			link.Fingerprint(),
+			link.ServiceID(),
+			reports.Category(c.FormValue("category")),
+			c.FormValue("comment"),
+		)
+		if err != nil {
+			if errors.Is(err, reports.ErrInvalidCategory) {
+				return oops(c, http.StatusBadRequest, false)
+			}
+			if errors.Is(err, reports.ErrCommentTooLong) {
+				return oops(c, http.StatusRequestEntityTooLarge, false)
+			}
+			s.logger.Error("failed to create a report", zap.Error(err))
+			return oops(c, http.StatusInternalServerError, false)
+		}
+
+		if err := badgerutil.Store(s.badgerDB, report); err != nil {
+			s.logger.Error("failed to store a report", zap.Error(err))
+			s.metrics.BadgerError("store_report")
+			return oops(c, http.StatusInternalServerError, false)
+		}
+		return c.Redirect(http.StatusSeeOther, "/links/"+link.Fingerprint()+"?reported")
+	}
+}
+
+func (s *server) handleAdminReports() echo.HandlerFunc {
+	type pageData struct {
+		Summaries  []*reports.Summary
+		Categories []reports.Category
+	}
+	return func(c echo.Context) error {
+		queue, err := reports.Queue(s.badgerDB)
+		if err != nil {
+			s.logger.Error("failed to list reports", zap.Error(err))
+			return c.String(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		}
+
+		pageContent := pageData{}
+		pageContent.Summaries = queue
+		pageContent.Categories = reports.Categories
+		return c.Render(http.StatusOK, "admin_reports", pageContent)
+	}
+}
+
+func (s *server) handleAdminReportsJSON() echo.HandlerFunc {
+	type response struct {
+		Links []apiReportSummary `json:"links"`
+	}
+	return func(c echo.Context) error {
+		queue, err := reports.Queue(s.badgerDB)
+		if err != nil {
+			s.logger.Error("failed to list reports", zap.Error(err))
+			return apiError(c, http.StatusInternalServerError)
+		}
+
+		resp := response{
+			Links: make([]apiReportSummary, 0, len(queue)),
+		}
+		for _, summary := range queue {
+			resp.Links = append(resp.Links, apiReportSummary{
+				Fingerprint:    summary.Fingerprint,
+				ServiceID:      summary.ServiceID,
+				Count:          summary.Count,
+				Categories:     summary.Categories,
+				LastReportedAt: summary.LastReportedAt,
+			})
+		}
+		return c.JSON(http.StatusOK, resp)
+	}
+}
+
+func (s *server) handleAdminLinkReportsJSON() echo.HandlerFunc {
+	type response struct {
+		Reports []apiReport `json:"reports"`
+	}
+	return func(c echo.Context) error {
+		result, err := reports.Load(s.badgerDB, c.Param("fp"))
+		if err != nil {
+			s.logger.Error("failed to load reports", zap.Error(err))
+			return apiError(c, http.StatusInternalServerError)
+		}
+
+		resp := response{
+			Reports: make([]apiReport, 0, len(result)),
+		}
+		for _, r := range result {
+			resp.Reports = append(resp.Reports, apiReport{
+				ID:        r.ID(),
+				Category:  r.Category(),
+				Comment:   r.Comment(),
+				CreatedAt: r.CreatedAt(),
+			})
+		}
+		return c.JSON(http.StatusOK, resp)
+	}
+}
+
+// handleAdminLinkReportsDismiss removes reports of the link from the review queue.
+func (s *server) handleAdminLinkReportsDismiss() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		n, err := reports.Dismiss(s.badgerDB, c.Param("fp"))
+		if err != nil {
+			s.logger.Error("failed to dismiss reports", zap.Error(err))
+			return apiError(c, http.StatusInternalServerError)
+		}
+		if n == 0 {
+			return apiError(c, http.StatusNotFound)
+		}
+
+		s.logger.Info("reports dismissed",
+			zap.String("fingerprint", c.Param("fp")),
+			zap.Int("reports", n),
+		)
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"dismissed_reports": n,
+		})
+	}
+}
@@ -8,7 +8,10 @@ import (
 )
 
 func (s *server) routes() {
-	s.router.GET("/", s.handlePage("home"))
+	s.router.Use(s.lockBadgerDB())
+	s.router.Use(s.selectLocale())
+
+	s.router.GET("/", s.handlePage("home"), s.startSession())
 	s.router.GET("/about", s.handlePage("about"))
 	s.router.GET("/privacy", s.handlePage("privacy"))
 	s.router.GET("/dyk", s.handlePage("dyk"))
@@ -26,14 +29,65 @@ func (s *server) routes() {
 			string(s.config.BackupAuth),
 		),
 	)
+	// Restore overwrites the database, it's available only if protected with a key.
+	if s.config.BackupAuth != "" {
+		s.router.POST("/backup/badgerdb",
+			s.handleRestoreBadgerDB(),
+			auth.KeyAuthWithConfig(
+				string(s.config.BackupAuth),
+			),
+		)
+	}
 
-	s.router.POST("/links/new", s.handleLinksNew(), s.solveCaptcha())
+	s.router.POST("/links/new", s.handleLinksNew(), s.startSession(), s.solveCaptcha())
 	s.router.GET("/links/oops/:id", s.handleOops(nil, true))
 	s.router.GET("/links/:fp", s.handleLinksView())
+	s.router.GET("/links/:fp/qr.png", s.handleLinksQRCode(qrPNG))
+	s.router.GET("/links/:fp/qr.svg", s.handleLinksQRCode(qrSVG))
+	s.router.POST("/links/:fp/report", s.handleLinksReport(), s.solveCaptcha())
+	s.router.GET("/links/:fp/manage", s.handleLinksManage())
+	s.router.POST("/links/:fp/manage", s.handleLinksManage())
 
+	s.router.GET("/to/:id", s.handleRedirect())
 	s.router.GET("/to/:id/:fp", s.handleRedirect())
 
-	s.router.GET("/sorry", s.handleCaptcha())
+	s.router.GET("/services/:id/keys/:key", s.handleServicePublicKey())
+
+	s.router.GET("/sorry", s.handleCaptcha(), s.startSession())
+
+	// JSON API is available only if protected with a key.
+	if s.config.APIAuth != "" {
+		api := s.router.Group("/api/v1",
+			auth.KeyAuthWithConfig(
+				string(s.config.APIAuth),
+			),
+		)
+		api.POST("/links", s.handleAPILinksCreate())
+		api.GET("/links/:fp", s.handleAPILinksGet())
+		api.GET("/links/:fp/resolve", s.handleAPILinksResolve())
+		if !s.config.NoStats {
+			api.GET("/links/:fp/stats", s.handleAPILinksStats())
+		}
+	}
+
+	// Admin pages are available only if protected with a key.
+	if s.config.AdminAuth != "" {
+		admin := s.router.Group("/admin",
+			auth.KeyAuthWithConfig(
+				string(s.config.AdminAuth),
+			),
+		)
+		admin.GET("/services/:id/links", s.handleAdminServiceLinks())
+		admin.GET("/services/:id/links.json", s.handleAdminServiceLinksJSON())
+		admin.GET("/moderation", s.handleAdminModeration())
+		admin.GET("/moderation.json", s.handleAdminModerationJSON())
+		admin.POST("/moderation/blocks", s.handleAdminModerationBlock())
+		admin.DELETE("/moderation/blocks/:id", s.handleAdminModerationUnblock())
+		admin.GET("/reports", s.handleAdminReports())
+		admin.GET("/reports.json", s.handleAdminReportsJSON())
+		admin.GET("/links/:fp/reports.json", s.handleAdminLinkReportsJSON())
+		admin.DELETE("/links/:fp/reports", s.handleAdminLinkReportsDismiss())
+	}
 
 	s.router.File("/robots.txt", s.config.WWWDir+"/robots.txt")
 
@@ -6,29 +6,54 @@ import (
 	"github.com/dgraph-io/badger/v2"
 	"github.com/labstack/echo/v4"
 	captcha "github.com/onionltd/mono/pkg/base64captcha"
+	"github.com/onionltd/mono/pkg/hashcash"
+	"github.com/onionltd/mono/pkg/i18n"
 	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
 	"github.com/onionltd/mono/services/vworp/badger/links"
+	"github.com/onionltd/mono/services/vworp/badger/reachability"
+	"github.com/onionltd/mono/services/vworp/badger/reports"
+	"github.com/onionltd/mono/services/vworp/badger/slugs"
+	"github.com/onionltd/mono/services/vworp/badger/stats"
+	"github.com/onionltd/mono/services/vworp/badger/submissions"
 	"github.com/oniontree-org/go-oniontree"
+	"github.com/oniontree-org/go-oniontree/scanner"
 	"github.com/oniontree-org/go-oniontree/scanner/evtcache"
 	"go.uber.org/zap"
 	"html/template"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
-	"sort"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+const tokenCookieName = "vworp_token"
+
 type server struct {
 	logger   *zap.Logger
 	router   *echo.Echo
 	captcha  *captcha.Captcha
+	pow      *hashcash.Hashcash
+	metrics  *appMetrics
+	limiter  *rateLimiter
 	cache    *evtcache.Cache
+	addrs    *addressIndex
+	blocks   *blocklist
+	mirrors  mirrorSelector
 	ot       *oniontree.OnionTree
 	badgerDB *badger.DB
 	config   *config
 	oopsSet  oopsSet
+	locales  *i18n.Bundle
+
+	// badgerDBLock is held exclusively while a backup is being restored.
+	badgerDBLock sync.RWMutex
+	restoring    int32
 }
 
 func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -36,11 +61,79 @@ func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *server) handleRedirect() echo.HandlerFunc {
+	type mirror struct {
+		Address string
+		Status  scanner.Status
+	}
+	type publicKey struct {
+		ID          string
+		UserID      string
+		Fingerprint string
+		// DownloadID identifies the key in download URL, empty if the armored key is not available.
+		DownloadID string
+	}
 	type pageData struct {
 		Service *oniontree.Service
 		Online  bool
-		Link    *links.Link
-		Mirror  string
+		// Link is nil if the user is redirected to the root of the service.
+		Link       *links.Link
+		Path       string
+		Mirror     string
+		Mirrors    []mirror
+		PublicKeys []publicKey
+		// Reachability is nil if the link's path has not been checked yet.
+		Reachability     *reachability.Reachability
+		ReportCategories []reports.Category
+	}
+	// formatFingerprint splits the fingerprint into groups of four characters.
+	formatFingerprint := func(fpr string) string {
+		groups := make([]string, 0, len(fpr)/4+1)
+		for len(fpr) > 4 {
+			groups = append(groups, fpr[:4])
+			fpr = fpr[4:]
+		}
+		return strings.Join(append(groups, fpr), " ")
+	}
+	listPublicKeys := func(service *oniontree.Service) []publicKey {
+		keys := make([]publicKey, 0, len(service.PublicKeys))
+		for _, key := range service.PublicKeys {
+			if key.ID == "" && key.Fingerprint == "" {
+				continue
+			}
+			pk := publicKey{
+				ID:          key.ID,
+				UserID:      key.UserID,
+				Fingerprint: formatFingerprint(key.Fingerprint),
+			}
+			if key.Value != "" {
+				pk.DownloadID = key.ID
+				if pk.DownloadID == "" {
+					pk.DownloadID = key.Fingerprint
+				}
+			}
+			keys = append(keys, pk)
+		}
+		return keys
+	}
+	// listMirrors returns all addresses of the service, online addresses first.
+	listMirrors := func(serviceID string) []mirror {
+		addrs, _ := s.cache.GetAddresses(serviceID)
+		online := make([]string, 0, len(addrs))
+		offline := make([]string, 0, len(addrs))
+		for addr, status := range addrs {
+			if status == scanner.StatusOffline {
+				offline = append(offline, addr)
+			} else {
+				online = append(online, addr)
+			}
+		}
+		sortAddresses(online)
+		sortAddresses(offline)
+		mirrors := make([]mirror, 0, len(addrs))
+		for _, addr := range append(online, offline...) {
+			mirrors = append(mirrors, mirror{addr, addrs[addr]})
+		}
+		return mirrors
 	}
 	isPreview := func(values url.Values) bool {
 		for key := range values {
@@ -50,15 +143,13 @@ func (s *server) handleRedirect() echo.HandlerFunc {
 		}
 		return false
 	}
-	sortAddressesV3First := func(addrs []string) {
-		sort.SliceStable(addrs, func(i, j int) bool {
-			return len(addrs[i]) > len(addrs[j])
-		})
-	}
 	oops := func(c echo.Context, code int, showSubmitForm bool) error {
+		s.metrics.Redirect(strconv.Itoa(code))
 		return s.handleOops(&code, false)(c)
 	}
 	return func(c echo.Context) error {
+		defer s.metrics.ObserveDuration("redirect", time.Now())
+
 		serviceID := c.Param("id")
 		fingerprint := c.Param("fp")
 
@@ -67,101 +158,169 @@ func (s *server) handleRedirect() echo.HandlerFunc {
 			return oops(c, http.StatusNotFound, false)
 		}
 
-		key := links.NewKey(fingerprint)
-		link := &links.Link{}
-		if err := badgerutil.Load(s.badgerDB, key, link); err != nil {
-			if errors.Is(err, badger.ErrKeyNotFound) {
+		if s.blocks.Blocked(service.ID(), "", "") {
+			return oops(c, http.StatusUnavailableForLegalReasons, false)
+		}
+
+		pageContent := pageData{}
+		pageContent.Service = service
+		pageContent.Path = "/"
+		pageContent.ReportCategories = reports.Categories
+
+		// Without a fingerprint, the user is redirected to the root of the service.
+		if fingerprint != "" {
+			link, code := s.loadLink(fingerprint)
+			if code != http.StatusOK {
+				return oops(c, code, false)
+			}
+
+			if link.ServiceID() != service.ID() {
 				return oops(c, http.StatusNotFound, false)
 			}
-			s.logger.Error("failed to read the database", zap.Error(err))
-			return oops(c, http.StatusInternalServerError, false)
-		}
 
-		if link.ServiceID() != service.ID() {
-			return oops(c, http.StatusNotFound, false)
+			pageContent.Link = link
+			pageContent.Path = link.Path()
 		}
 
-		online, _ := s.cache.GetOnlineAddresses(service.ID())
-		sortAddressesV3First(online)
-
-		pageContent := pageData{}
-		pageContent.Service = service
-		pageContent.Link = link
-		pageContent.Online = len(online) > 0
+		pageContent.Mirror = s.selectMirror(service.ID())
+		pageContent.Online = pageContent.Mirror != ""
 
-		if len(online) > 0 {
-			pageContent.Mirror = online[0]
+		if pageContent.Link != nil && !isPreview(c.QueryParams()) {
+			s.countRedirect(pageContent.Link)
 		}
 
 		// If there'a an active mirror and preview is disabled, redirect immediately.
 		if pageContent.Mirror != "" && !isPreview(c.QueryParams()) {
-			dest := pageContent.Mirror + link.Path()
+			dest := pageContent.Mirror + pageContent.Path
+			s.metrics.Redirect("redirected")
 			return c.Redirect(http.StatusSeeOther, dest)
 		}
 
+		if pageContent.Online {
+			s.metrics.Redirect("preview")
+		} else {
+			s.metrics.Redirect("offline")
+		}
+
+		pageContent.Mirrors = listMirrors(service.ID())
+		pageContent.PublicKeys = listPublicKeys(service)
+
+		if pageContent.Link != nil {
+			r, err := reachability.Load(s.badgerDB, pageContent.Link.Fingerprint())
+			if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+				s.logger.Error("failed to load reachability", zap.Error(err))
+				s.metrics.BadgerError("load_reachability")
+			}
+			pageContent.Reachability = r
+		}
+
 		return c.Render(http.StatusOK, "redirect", pageContent)
 	}
 }
 
-func (s *server) handleLinksNew() echo.HandlerFunc {
-	oops := func(c echo.Context, code int) error {
-		return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/links/oops/%d", code))
+func (s *server) handleServicePublicKey() echo.HandlerFunc {
+	findPublicKey := func(service *oniontree.Service, id string) *oniontree.PublicKey {
+		for _, key := range service.PublicKeys {
+			if key.Value == "" {
+				continue
+			}
+			if strings.EqualFold(key.ID, id) || strings.EqualFold(key.Fingerprint, id) {
+				return key
+			}
+		}
+		return nil
 	}
 	return func(c echo.Context) error {
-		u, err := url.Parse(
-			strings.TrimSpace(c.FormValue("link")),
-		)
-		if err != nil || u.Scheme == "" || u.Host == "" {
-			return oops(c, http.StatusBadRequest)
+		service, err := s.ot.GetService(c.Param("id"))
+		if err != nil {
+			return echo.ErrNotFound
 		}
 
-		// Force root directory, if not present.
-		if u.Path == "" {
-			u.Path = "/"
+		id := strings.TrimSuffix(c.Param("key"), ".asc")
+		key := findPublicKey(service, id)
+		if key == nil {
+			return echo.ErrNotFound
 		}
 
-		serviceID, ok := s.cache.GetServiceID(
-			fmt.Sprintf("%s://%s", u.Scheme, u.Host),
-		)
-		if !ok {
-			return oops(c, http.StatusNotFound)
-		}
+		filename := fmt.Sprintf("%s-%s.asc", service.ID(), id)
+		c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+		return c.Blob(http.StatusOK, "application/pgp-keys", []byte(key.Value))
+	}
+}
 
-		// Someone just pasted a vworp! link
-		if serviceID == "vworp" {
-			return oops(c, http.StatusNotAcceptable)
+func (s *server) handleLinksNew() echo.HandlerFunc {
+	oops := func(c echo.Context, code int) error {
+		return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/links/oops/%d", code))
+	}
+	// setTokenCookie passes the management token to the next page, without leaking it into the URL.
+	setTokenCookie := func(c echo.Context, link *links.Link, token string) {
+		c.SetCookie(&http.Cookie{
+			Name:     tokenCookieName,
+			Value:    token,
+			Path:     "/links/" + link.Fingerprint(),
+			MaxAge:   300,
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+	}
+	// session returns ID of the user's session. The session is started on the challenge page at the latest,
+	// so the user has none only if they don't keep cookies. Such users share the anonymous session.
+	session := func(c echo.Context) string {
+		if cookie, err := c.Cookie(sessionCookieName); err == nil && cookie.Value != "" {
+			return cookie.Value
 		}
+		return anonymousSession
+	}
+	return func(c echo.Context) error {
+		defer s.metrics.ObserveDuration("links_new", time.Now())
 
-		path := (&url.URL{
-			Path:     u.Path,
-			RawQuery: u.RawQuery,
-			Fragment: u.Fragment,
-		}).String()
-
-		link, err := links.NewLink(serviceID, path)
-		if err != nil {
-			s.logger.Error("failed to create a new link", zap.Error(err))
-			return oops(c, http.StatusInternalServerError)
+		link, token, code := s.createLink(session(c), c.FormValue("link"), c.FormValue("expires"), c.FormValue("slug"))
+		s.metrics.LinkSubmitted(code)
+		if code != http.StatusOK {
+			return oops(c, code)
 		}
-
-		if err := badgerutil.Store(s.badgerDB, link); err != nil {
-			s.logger.Error("failed to update the database", zap.Error(err))
-			return oops(c, http.StatusInternalServerError)
+		if token != "" {
+			setTokenCookie(c, link, token)
 		}
-
 		return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/links/%s?new", link.Fingerprint()))
 	}
 }
 
 func (s *server) handleLinksView() echo.HandlerFunc {
+	type linkStats struct {
+		// Total counts redirects within the retention period, older counts are not kept.
+		Total         uint64
+		LastMonth     uint64
+		RetentionDays int
+	}
 	type pageData struct {
 		Section       string
 		Service       *oniontree.Service
 		ServerAddress string
 		Link          *links.Link
+		Token         string
+		// Stats is nil if statistics are disabled.
+		Stats            *linkStats
+		ReportCategories []reports.Category
+	}
+	// popToken returns the management token, which is shown only once.
+	popToken := func(c echo.Context, link *links.Link) string {
+		cookie, err := c.Cookie(tokenCookieName)
+		if err != nil {
+			return ""
+		}
+		c.SetCookie(&http.Cookie{
+			Name:   tokenCookieName,
+			Path:   "/links/" + link.Fingerprint(),
+			MaxAge: -1,
+		})
+		if !link.VerifyToken(cookie.Value) {
+			return ""
+		}
+		return cookie.Value
 	}
 	queryParamsToSectionName := func(values url.Values) string {
-		sections := []string{"new"}
+		sections := []string{"new", "stats", "reported"}
 		for key := range values {
 			for _, section := range sections {
 				if key == section {
@@ -171,20 +330,25 @@ func (s *server) handleLinksView() echo.HandlerFunc {
 		}
 		return ""
 	}
+	countView := func(c echo.Context, code int) {
+		section := queryParamsToSectionName(c.QueryParams())
+		if section == "" {
+			section = "none"
+		}
+		s.metrics.LinkViewed(section, code)
+	}
 	oops := func(c echo.Context, code int, showSubmitForm bool) error {
+		countView(c, code)
 		return s.handleOops(&code, false)(c)
 	}
 	return func(c echo.Context) error {
+		defer s.metrics.ObserveDuration("links_view", time.Now())
+
 		fingerprint := c.Param("fp")
 
-		key := links.NewKey(fingerprint)
-		link := &links.Link{}
-		if err := badgerutil.Load(s.badgerDB, key, link); err != nil {
-			if errors.Is(err, badger.ErrKeyNotFound) {
-				return oops(c, http.StatusNotFound, false)
-			}
-			s.logger.Error("failed to read the database", zap.Error(err))
-			return oops(c, http.StatusInternalServerError, false)
+		link, code := s.loadLink(fingerprint)
+		if code != http.StatusOK {
+			return oops(c, code, false)
 		}
 
 		service, err := s.ot.GetService(link.ServiceID())
@@ -197,11 +361,160 @@ func (s *server) handleLinksView() echo.HandlerFunc {
 		pageContent.Service = service
 		pageContent.Link = link
 		pageContent.ServerAddress = c.Request().Host
+		pageContent.ReportCategories = reports.Categories
 
+		if pageContent.Section == "new" {
+			pageContent.Token = popToken(c, link)
+		}
+
+		if pageContent.Section == "stats" && !s.config.NoStats {
+			days, err := s.loadStats(link)
+			if err != nil {
+				s.logger.Error("failed to load stats", zap.Error(err))
+				s.metrics.BadgerError("load_stats")
+				return oops(c, http.StatusInternalServerError, false)
+			}
+			monthAgo := time.Now().AddDate(0, 0, -30)
+			lastMonth := make([]*stats.DayCount, 0, len(days))
+			for _, day := range days {
+				if day.Day().After(monthAgo) {
+					lastMonth = append(lastMonth, day)
+				}
+			}
+			pageContent.Stats = &linkStats{
+				Total:         stats.Total(days),
+				LastMonth:     stats.Total(lastMonth),
+				RetentionDays: int(s.config.StatsRetention.Hours() / 24),
+			}
+		}
+
+		countView(c, http.StatusOK)
 		return c.Render(http.StatusOK, "links_view", pageContent)
 	}
 }
 
+func (s *server) handleLinksManage() echo.HandlerFunc {
+	type pageData struct {
+		Section       string
+		Service       *oniontree.Service
+		ServerAddress string
+		Link          *links.Link
+		// Kept is true if the link was shared, so it was kept in place when it was changed.
+		Kept bool
+	}
+	oops := func(c echo.Context, code int, showSubmitForm bool) error {
+		return s.handleOops(&code, false)(c)
+	}
+	return func(c echo.Context) error {
+		fingerprint := c.Param("fp")
+
+		link, code := s.loadLink(fingerprint)
+		if code != http.StatusOK {
+			return oops(c, code, false)
+		}
+
+		if !link.HasToken() {
+			return oops(c, http.StatusForbidden, false)
+		}
+
+		service, err := s.ot.GetService(link.ServiceID())
+		if err != nil {
+			return oops(c, http.StatusNotFound, false)
+		}
+
+		pageContent := pageData{}
+		pageContent.Service = service
+		pageContent.Link = link
+		pageContent.ServerAddress = c.Request().Host
+
+		if c.Request().Method != http.MethodPost {
+			return c.Render(http.StatusOK, "links_manage", pageContent)
+		}
+
+		if !link.VerifyToken(strings.TrimSpace(c.FormValue("token"))) {
+			return oops(c, http.StatusUnauthorized, false)
+		}
+
+		switch c.FormValue("action") {
+		case "delete":
+			// Others who submitted the same URL were given the link too.
+			if link.Shared() {
+				return oops(c, http.StatusForbidden, false)
+			}
+			err := s.badgerDB.Update(func(txn *badger.Txn) error {
+				if link.Slug() != "" {
+					if err := txn.Delete(slugs.NewKey(link.Slug())); err != nil {
+						return err
+					}
+				}
+				if err := stats.DeleteTxn(txn, link.Fingerprint()); err != nil {
+					return err
+				}
+				if err := txn.Delete(reachability.NewKey(link.Fingerprint())); err != nil {
+					return err
+				}
+				return links.DeleteTxn(txn, link)
+			})
+			if err != nil {
+				s.logger.Error("failed to update the database", zap.Error(err))
+				return oops(c, http.StatusInternalServerError, false)
+			}
+			pageContent.Section = "deleted"
+
+		case "edit":
+			path, ok := parsePath(c.FormValue("path"))
+			if !ok {
+				return oops(c, http.StatusBadRequest, false)
+			}
+			if s.blocks.Blocked(link.ServiceID(), link.Fingerprint(), path) {
+				return oops(c, http.StatusUnavailableForLegalReasons, false)
+			}
+			// Fingerprints are derived from the path, so the link is moved to the fingerprint of the new path.
+			// If the link is shared, the others keep the old one.
+			var moved *links.Link
+			err := s.badgerDB.Update(func(txn *badger.Txn) (err error) {
+				moved, err = links.MoveTxn(txn, link, path)
+				if err != nil {
+					return err
+				}
+				if s.blocks.BlockedLink(moved) {
+					return errLinkBlocked
+				}
+				if moved.Slug() != "" {
+					if err := badgerutil.StoreTxn(txn, slugs.NewSlug(moved.Slug(), moved.Fingerprint(), moved.Expires())); err != nil {
+						return err
+					}
+				}
+				if link.Shared() {
+					return nil
+				}
+				if err := stats.DeleteTxn(txn, link.Fingerprint()); err != nil {
+					return err
+				}
+				return txn.Delete(reachability.NewKey(link.Fingerprint()))
+			})
+			if err != nil {
+				if errors.Is(err, links.ErrExists) {
+					return oops(c, http.StatusConflict, false)
+				}
+				if errors.Is(err, errLinkBlocked) {
+					return oops(c, http.StatusUnavailableForLegalReasons, false)
+				}
+				s.logger.Error("failed to update the database", zap.Error(err))
+				return oops(c, http.StatusInternalServerError, false)
+			}
+			pageContent.Link = moved
+			pageContent.Kept = link.Shared()
+			pageContent.Section = "updated"
+
+		default:
+			return oops(c, http.StatusBadRequest, false)
+		}
+
+		return c.Render(http.StatusOK, "links_manage", pageContent)
+	}
+}
+
 func (s *server) handleOops(oopsID *int, showSubmitForm bool) echo.HandlerFunc {
 	type oopsPageContent struct {
 		OopsMessage    string
@@ -210,7 +523,7 @@ func (s *server) handleOops(oopsID *int, showSubmitForm bool) echo.HandlerFunc {
 	newOopsPageContent := func(c echo.Context, code int, showSubmitForm bool) oopsPageContent {
 		oopsSet := s.oopsSet[c.Path()]
 		return oopsPageContent{
-			OopsMessage: oopsSet.Get(code),
+			OopsMessage: s.translate(c, oopsSet.Get(code)),
 		}
 	}
 	deduceStatusCode := func(oopsID int) int {
@@ -233,16 +546,31 @@ func (s *server) handleOops(oopsID *int, showSubmitForm bool) echo.HandlerFunc {
 func (s *server) handleCaptcha() echo.HandlerFunc {
 	type pageData struct {
 		CaptchaBase64 template.URL
-		QueryParams   url.Values
+		SubmissionID  string
+		Continue      string
+		PoWNonce      string
+		PoWDifficulty int
 	}
 	return func(c echo.Context) error {
-		b64, err := s.captcha.GetImageData(c.QueryParam("cid"))
+		sub, err := submissions.Load(s.badgerDB, c.QueryParam("submission"))
+		if err != nil {
+			return c.Redirect(http.StatusSeeOther, "/")
+		}
+		values := sub.Values()
+
+		b64, err := s.captcha.GetImageData(values.Get("cid"))
 		if err != nil {
 			return c.Redirect(http.StatusSeeOther, "/")
 		}
 		pageContent := pageData{}
 		pageContent.CaptchaBase64 = template.URL(b64)
-		pageContent.QueryParams = c.QueryParams()
+		pageContent.SubmissionID = sub.ID()
+		pageContent.Continue = values.Get("continue")
+
+		if nonce := values.Get("pow"); s.pow != nil && s.pow.Valid(nonce) {
+			pageContent.PoWNonce = nonce
+			pageContent.PoWDifficulty = s.pow.Difficulty()
+		}
 		return c.Render(http.StatusOK, "captcha", pageContent)
 	}
 }
@@ -276,6 +604,79 @@ func (s *server) handleBackupBadgerDB() echo.HandlerFunc {
 	}
 }
 
+func (s *server) handleRestoreBadgerDB() echo.HandlerFunc {
+	openUpload := func(c echo.Context) (io.ReadCloser, error) {
+		if file, err := c.FormFile("backup"); err == nil {
+			return file.Open()
+		}
+		return c.Request().Body, nil
+	}
+	// spoolUpload copies the upload to a temporary file so it can be read twice.
+	// The upload is limited in size, so that it can't fill the disk.
+	spoolUpload := func(c echo.Context) (*os.File, error) {
+		req := c.Request()
+		req.Body = http.MaxBytesReader(c.Response(), req.Body, s.config.BackupMaxUploadSize)
+
+		src, err := openUpload(c)
+		if err != nil {
+			return nil, err
+		}
+		defer src.Close()
+
+		f, err := ioutil.TempFile("", "vworp-restore")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(f, src); err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return nil, err
+		}
+		return f, nil
+	}
+	return func(c echo.Context) error {
+		if !atomic.CompareAndSwapInt32(&s.restoring, 0, 1) {
+			return c.String(http.StatusConflict, "another restore is in progress")
+		}
+		defer atomic.StoreInt32(&s.restoring, 0)
+
+		f, err := spoolUpload(c)
+		if err != nil {
+			s.logger.Error("failed to read the backup", zap.Error(err))
+			return c.String(http.StatusBadRequest, "failed to read the backup")
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+
+		keys, err := badgerutil.VerifyBackup(f)
+		if err != nil {
+			s.logger.Warn("refused to restore a corrupted backup", zap.Error(err))
+			return c.String(http.StatusBadRequest, "the backup is corrupted")
+		}
+
+		s.badgerDBLock.Lock()
+		err = s.badgerDB.Load(f, badgerutil.LoadMaxPendingWrites)
+		if err == nil {
+			// The backup may come from an older schema.
+			_, err = badgerutil.Remigrate(s.badgerDB, migrations)
+		}
+		if err == nil {
+			// Blocks in memory must match the restored database.
+			err = s.blocks.Load(s.badgerDB)
+		}
+		s.badgerDBLock.Unlock()
+		if err != nil {
+			s.logger.Error("failed to restore the badger database", zap.Error(err))
+			return err
+		}
+
+		s.logger.Info("badger database restored", zap.Int("keys", keys))
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"restored_keys": keys,
+		})
+	}
+}
+
 func (s *server) handlePage(name string) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		return c.Render(http.StatusOK, name, nil)
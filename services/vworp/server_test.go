@@ -0,0 +1,463 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	badger "github.com/dgraph-io/badger/v2"
+	"github.com/jessevdk/go-flags"
+	"github.com/labstack/echo/v4"
+	echoerrors "github.com/onionltd/mono/pkg/echo/errors"
+	badgerutil "github.com/onionltd/mono/pkg/utils/badger"
+	"github.com/onionltd/mono/services/vworp/badger/links"
+	"github.com/onionltd/mono/services/vworp/badger/slugs"
+	"github.com/oniontree-org/go-oniontree"
+	"github.com/oniontree-org/go-oniontree/scanner"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testServiceID = "example"
+	testMirror    = "http://2jwcnprqbugvyi6ok2h2h7u26qc6j5wxm7feh3znlh2qu3h6hjld4kyd.onion"
+)
+
+type testServer struct {
+	*server
+	eventCh chan scanner.Event
+}
+
+// newTestServer returns a server of a single service with a single mirror. Extra args are parsed
+// the same way as command line arguments.
+func newTestServer(t *testing.T, args ...string) *testServer {
+	tempDir, err := ioutil.TempDir("/tmp", "vworp-ut")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tempDir) })
+
+	ot := oniontree.New(tempDir + "/oniontree")
+	if err := ot.Init(); err != nil {
+		t.Fatal(err)
+	}
+	service := oniontree.NewService(testServiceID)
+	service.Name = "Example"
+	service.SetURLs([]string{testMirror})
+	if err := ot.AddService(service); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config{}
+	args = append([]string{
+		"--www", "public",
+		"--templates", "templates/*.html",
+		"--locales", "locales",
+		"--oniontree", tempDir + "/oniontree",
+		"--badgerdb", tempDir + "/badgerdb",
+	}, args...)
+	if _, err := flags.NewParser(cfg, flags.None).ParseArgs(args); err != nil {
+		t.Fatal(err)
+	}
+
+	locales, err := setupLocales(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates, err := setupTemplates(zap.NewNop(), cfg, locales)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := setupBadger(zap.NewNop(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	blocks, err := setupBlocklist(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	cache := setupEventCache()
+	eventCh := make(chan scanner.Event)
+	go cache.ReadEvents(ctx, eventCh, nil)
+
+	// Metrics are not registered, tests would collide with each other.
+	router := echo.New()
+	router.Renderer = templates
+	router.HTTPErrorHandler = echoerrors.DefaultErrorHandler
+
+	s := &testServer{
+		server: &server{
+			logger:   zap.NewNop(),
+			config:   cfg,
+			router:   router,
+			cache:    cache,
+			addrs:    setupAddressIndex(),
+			blocks:   blocks,
+			mirrors:  setupMirrorSelector(cfg, setupMirrorFailures()),
+			badgerDB: db,
+			ot:       ot,
+			oopsSet:  oopsies,
+			locales:  locales,
+			captcha:  setupCaptcha(zap.NewNop(), cfg, db),
+			pow:      setupProofOfWork(cfg),
+			metrics:  newAppMetrics(),
+			limiter:  newRateLimiter(db, cfg),
+		},
+		eventCh: eventCh,
+	}
+	s.addrs.add(testServiceID, testMirror)
+	s.routes()
+	return s
+}
+
+// setMirrorStatus returns once the cache knows the status of the mirror.
+func (s *testServer) setMirrorStatus(status scanner.Status) {
+	s.eventCh <- scanner.ScanEvent{Status: status, URL: testMirror, ServiceID: testServiceID}
+	// The cache has processed the first event once it receives the second one.
+	s.eventCh <- scanner.ScanEvent{Status: scanner.StatusOffline, URL: "http://other.onion", ServiceID: "other"}
+}
+
+// storeLink stores a link to the test service, with a slug if it's not empty.
+func (s *testServer) storeLink(t *testing.T, path, slug string) *links.Link {
+	link, err := links.NewLink(testServiceID, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	link.SetExpiresAt(time.Now().Add(24 * time.Hour))
+	if slug != "" {
+		link.SetSlug(slug)
+	}
+	err = s.badgerDB.Update(func(txn *badger.Txn) error {
+		if err := links.StoreTxn(txn, link); err != nil {
+			return err
+		}
+		if slug == "" {
+			return nil
+		}
+		return slugs.StoreTxn(txn, slugs.NewSlug(slug, link.Fingerprint(), link.ExpiresAt()))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return link
+}
+
+func (s *testServer) post(target string, form url.Values, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+// submitLink submits the form on the home page and solves the proof-of-work challenge,
+// which must have zero difficulty. It returns the final response.
+func (s *testServer) submitLink(t *testing.T, form url.Values, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	rec := s.post("/links/new", form, cookies...)
+	if !assert.Equal(t, http.StatusSeeOther, rec.Code) {
+		return rec
+	}
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, "/sorry", location.Path) {
+		return rec
+	}
+	solved := url.Values{}
+	solved.Set("submission", location.Query().Get("submission"))
+	solved.Set("pow_solution", "0")
+	return s.post("/links/new", solved, cookies...)
+}
+
+// sessionCookie returns the session cookie set by the response, or nil.
+func sessionCookie(rec *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == sessionCookieName {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func (s *testServer) get(target string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandleRedirectPages(t *testing.T) {
+	s := newTestServer(t)
+	link := s.storeLink(t, "/page", "my-page")
+	reportAction := fmt.Sprintf(`action="/links/%s/report"`, link.Fingerprint())
+
+	for _, status := range []scanner.Status{scanner.StatusOnline, scanner.StatusOffline} {
+		s.setMirrorStatus(status)
+		for _, id := range []string{link.Fingerprint(), "my-page"} {
+			for _, lang := range []string{"en", "de"} {
+				target := fmt.Sprintf("/to/%s/%s?preview&lang=%s", testServiceID, id, lang)
+				rec := s.get(target)
+				if assert.Equal(t, http.StatusOK, rec.Code, target) {
+					assert.Contains(t, rec.Body.String(), reportAction, target)
+					assert.Contains(t, rec.Body.String(), `<option value="phishing">`, target)
+				}
+			}
+		}
+	}
+
+	// Without an online mirror, the interstitial is shown even without preview.
+	rec := s.get(fmt.Sprintf("/to/%s/%s", testServiceID, link.Fingerprint()))
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		assert.Contains(t, rec.Body.String(), reportAction)
+	}
+
+	// Without preview, users are redirected to an online mirror.
+	s.setMirrorStatus(scanner.StatusOnline)
+	rec = s.get(fmt.Sprintf("/to/%s/%s", testServiceID, link.Fingerprint()))
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, testMirror+"/page", rec.Header().Get("Location"))
+}
+
+func TestHandleLinksNewSessions(t *testing.T) {
+	s := newTestServer(t,
+		"--challenge", "pow",
+		"--pow-difficulty", "0",
+		"--rate-limit-session", "1",
+	)
+	form := func(path string) url.Values {
+		v := url.Values{}
+		v.Set("link", testMirror+path)
+		return v
+	}
+	// newClient returns the session cookie of a client that visited the home page.
+	newClient := func() *http.Cookie {
+		cookie := sessionCookie(s.get("/"))
+		if !assert.NotNil(t, cookie) {
+			t.FailNow()
+		}
+		return cookie
+	}
+	assertCreated := func(rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusSeeOther, rec.Code)
+		assert.True(t, strings.HasSuffix(rec.Header().Get("Location"), "?new"), rec.Header().Get("Location"))
+	}
+	assertRateLimited := func(rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusSeeOther, rec.Code)
+		assert.Equal(t, "/links/oops/429", rec.Header().Get("Location"))
+	}
+
+	// Two new clients don't share the limit.
+	first := newClient()
+	second := newClient()
+	assert.NotEqual(t, first.Value, second.Value)
+	assertCreated(s.submitLink(t, form("/first"), first))
+	assertCreated(s.submitLink(t, form("/second"), second))
+	assertRateLimited(s.submitLink(t, form("/first/again"), first))
+
+	// The session is started on the challenge page too.
+	rec := s.post("/links/new", form("/third"))
+	third := sessionCookie(rec)
+	if assert.NotNil(t, third) {
+		location := rec.Header().Get("Location")
+		assert.NotNil(t, sessionCookie(s.get(location)))
+		assert.Nil(t, sessionCookie(s.get(location, third)))
+		assertCreated(s.submitLink(t, form("/third"), third))
+	}
+
+	// Clients that don't send the cookie back share the anonymous session.
+	assertCreated(s.submitLink(t, form("/anonymous")))
+	assertRateLimited(s.submitLink(t, form("/anonymous/again")))
+}
+
+func TestHandleLinksManageShared(t *testing.T) {
+	s := newTestServer(t, "--challenge", "pow", "--pow-difficulty", "0")
+	form := url.Values{}
+	form.Set("link", testMirror+"/shared")
+
+	// submit returns the address of the new link and its management token.
+	submit := func() (string, string) {
+		rec := s.submitLink(t, form, sessionCookie(s.get("/")))
+		location := rec.Header().Get("Location")
+		if !assert.True(t, strings.HasSuffix(location, "?new"), location) {
+			t.FailNow()
+		}
+		for _, cookie := range rec.Result().Cookies() {
+			if cookie.Name == tokenCookieName {
+				return location, cookie.Value
+			}
+		}
+		return location, ""
+	}
+	manage := func(fingerprint string, values url.Values) *httptest.ResponseRecorder {
+		return s.post("/links/"+fingerprint+"/manage", values)
+	}
+
+	firstLocation, token := submit()
+	assert.NotEmpty(t, token)
+	secondLocation, secondToken := submit()
+	assert.Equal(t, firstLocation, secondLocation)
+	assert.Empty(t, secondToken)
+
+	// The second submitter is told the link is managed by someone else.
+	rec := s.get(secondLocation)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Someone else submitted the same link before you.")
+
+	link, code := s.loadLink(strings.TrimSuffix(strings.TrimPrefix(firstLocation, "/links/"), "?new"))
+	if !assert.Equal(t, http.StatusOK, code) {
+		t.FailNow()
+	}
+	assert.True(t, link.Shared())
+
+	values := url.Values{}
+	values.Set("token", token)
+	values.Set("action", "delete")
+	assert.Equal(t, http.StatusForbidden, manage(link.Fingerprint(), values).Code)
+
+	values.Set("action", "edit")
+	values.Set("path", "/moved")
+	rec = manage(link.Fingerprint(), values)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		assert.Contains(t, rec.Body.String(), "The old address keeps working")
+	}
+
+	// Both links work, the first submitter manages only the new one.
+	_, code = s.loadLink(link.Fingerprint())
+	assert.Equal(t, http.StatusOK, code)
+	moved, err := links.NewLink(testServiceID, "/moved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved, code = s.loadLink(moved.Fingerprint())
+	if assert.Equal(t, http.StatusOK, code) {
+		assert.False(t, moved.Shared())
+		assert.True(t, moved.VerifyToken(token))
+	}
+
+	// An unshared link can be deleted.
+	values.Set("action", "delete")
+	assert.Equal(t, http.StatusOK, manage(moved.Fingerprint(), values).Code)
+	_, code = s.loadLink(moved.Fingerprint())
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestHandleRestoreBadgerDB(t *testing.T) {
+	const key = "secret"
+	s := newTestServer(t, "--backup-auth", key, "--backup-max-upload-size", "65536")
+	link := s.storeLink(t, "/live", "")
+
+	restore := func(body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/backup/badgerdb", bytes.NewReader(body))
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+key)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+	countKeys := func() int {
+		keys, err := badgerutil.CountKeys(s.badgerDB, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return keys
+	}
+
+	// Backup of another database, with a link of its own.
+	other := newTestServer(t)
+	restored := other.storeLink(t, "/restored", "")
+	backup := &bytes.Buffer{}
+	if _, err := other.badgerDB.Backup(backup, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// The live database is untouched if the backup is refused.
+	keys := countKeys()
+	for name, body := range map[string][]byte{
+		"garbage":   bytes.Repeat([]byte{0xff}, 64),
+		"truncated": backup.Bytes()[:backup.Len()-1],
+		"too large": append(backup.Bytes(), make([]byte, 65536)...),
+	} {
+		assert.Equal(t, http.StatusBadRequest, restore(body).Code, name)
+		assert.Equal(t, keys, countKeys(), name)
+		_, code := s.loadLink(restored.Fingerprint())
+		assert.Equal(t, http.StatusNotFound, code, name)
+	}
+
+	rec := restore(backup.Bytes())
+	assert.Equal(t, http.StatusOK, rec.Code)
+	_, code := s.loadLink(restored.Fingerprint())
+	assert.Equal(t, http.StatusOK, code)
+	_, code = s.loadLink(link.Fingerprint())
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestFooterLanguageLinks(t *testing.T) {
+	s := newTestServer(t)
+	link := s.storeLink(t, "/page", "")
+
+	rec := s.get(fmt.Sprintf("/to/%s/%s?preview&lang=en", testServiceID, link.Fingerprint()))
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		// Only the language is changed, the page stays in preview.
+		assert.Contains(t, rec.Body.String(), `href="?lang=de&amp;preview="`)
+		assert.Contains(t, rec.Body.String(), `href="?lang=en&amp;preview="`)
+	}
+
+	rec = s.get("/about?lang=de")
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		assert.Contains(t, rec.Body.String(), `href="?lang=en"`)
+	}
+}
+
+func TestHandleLinksNewSlugOfExistingLink(t *testing.T) {
+	s := newTestServer(t, "--challenge", "pow", "--pow-difficulty", "0")
+	submit := func(path, slug string) *httptest.ResponseRecorder {
+		form := url.Values{}
+		form.Set("link", testMirror+path)
+		form.Set("slug", slug)
+		return s.submitLink(t, form, sessionCookie(s.get("/")))
+	}
+
+	unnamed := submit("/unnamed", "")
+	assert.True(t, strings.HasSuffix(unnamed.Header().Get("Location"), "?new"))
+	named := submit("/named", "named")
+	assert.True(t, strings.HasSuffix(named.Header().Get("Location"), "?new"))
+
+	// Someone else can't name a link they didn't create.
+	rec := submit("/unnamed", "stolen")
+	assert.Equal(t, "/links/oops/403", rec.Header().Get("Location"))
+	_, err := slugs.Load(s.badgerDB, "stolen")
+	assert.Equal(t, badger.ErrKeyNotFound, err)
+	_, code := s.loadLink("stolen")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	rec = submit("/named", "renamed")
+	assert.Equal(t, "/links/oops/403", rec.Header().Get("Location"))
+
+	// Submitting the link under its own name, or without one, is fine.
+	rec = submit("/named", "named")
+	assert.Equal(t, named.Header().Get("Location"), rec.Header().Get("Location"))
+	rec = submit("/unnamed", "")
+	assert.Equal(t, unnamed.Header().Get("Location"), rec.Header().Get("Location"))
+
+	link, code := s.loadLink(strings.TrimSuffix(strings.TrimPrefix(unnamed.Header().Get("Location"), "/links/"), "?new"))
+	if assert.Equal(t, http.StatusOK, code) {
+		assert.Empty(t, link.Slug())
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"github.com/dgraph-io/badger/v2"
+	"github.com/onionltd/mono/services/vworp/badger/links"
+	"github.com/onionltd/mono/services/vworp/badger/stats"
+	"go.uber.org/zap"
+	"time"
+)
+
+// statsMaxRetries limits how many times a redirect is counted again on conflict.
+const statsMaxRetries = 3
+
+// countRedirect records a redirect of the link. Only the number of redirects per day is recorded.
+func (s *server) countRedirect(link *links.Link) {
+	if s.config.NoStats {
+		return
+	}
+	for i := 0; i < statsMaxRetries; i++ {
+		err := s.badgerDB.Update(func(txn *badger.Txn) error {
+			return stats.IncrementTxn(txn, link.Fingerprint(), time.Now(), s.config.StatsRetention)
+		})
+		if errors.Is(err, badger.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			s.logger.Error("failed to count a redirect", zap.Error(err))
+			s.metrics.BadgerError("count_redirect")
+		}
+		return
+	}
+	s.logger.Debug("redirect not counted due to conflicts")
+}
+
+// loadStats returns redirect counts of the link, nil if statistics are disabled.
+func (s *server) loadStats(link *links.Link) ([]*stats.DayCount, error) {
+	if s.config.NoStats {
+		return nil, nil
+	}
+	return stats.Load(s.badgerDB, link.Fingerprint())
+}
@@ -1,27 +1,82 @@
 package main
 
 import (
+	"fmt"
 	"github.com/labstack/echo/v4"
+	"github.com/onionltd/mono/pkg/i18n"
 	"go.uber.org/zap"
+	"golang.org/x/text/language"
 	"html/template"
 	"io"
 )
 
 type Templates struct {
-	logger    *zap.Logger
-	templates *template.Template
+	logger  *zap.Logger
+	locales *i18n.Bundle
+	// templates are parsed once for every supported language.
+	templates map[language.Tag]*template.Template
 }
 
-func (t *Templates) Load(pattern string) (err error) {
-	t.templates, err = template.ParseGlob(pattern)
-	if err != nil {
-		return err
+// funcs returns template functions bound to the language.
+func (t *Templates) funcs(tag language.Tag) template.FuncMap {
+	return template.FuncMap{
+		// t translates the message. Catalogs are trusted to contain safe HTML, arguments are escaped
+		// and formatted as strings, so messages should use only %s verbs.
+		"t": func(msg string, args ...interface{}) template.HTML {
+			escaped := make([]interface{}, 0, len(args))
+			for _, arg := range args {
+				if html, ok := arg.(template.HTML); ok {
+					escaped = append(escaped, html)
+					continue
+				}
+				escaped = append(escaped, template.HTMLEscapeString(fmt.Sprint(arg)))
+			}
+			return template.HTML(t.locales.Translate(tag, msg, escaped...))
+		},
+		"lang": func() string {
+			return tag.String()
+		},
+		"languages": func() []language.Tag {
+			return t.locales.Languages()
+		},
+		// langURL is replaced in Render with a function that knows the current URL.
+		"langURL": func(tag language.Tag) string {
+			return "?lang=" + tag.String()
+		},
+	}
+}
+
+func (t *Templates) Load(pattern string) error {
+	t.templates = make(map[language.Tag]*template.Template)
+	for _, tag := range t.locales.Languages() {
+		tpl, err := template.New("").Funcs(t.funcs(tag)).ParseGlob(pattern)
+		if err != nil {
+			return err
+		}
+		t.templates[tag] = tpl
 	}
 	return nil
 }
 
 func (t *Templates) Render(w io.Writer, name string, data interface{}, c echo.Context) error {
-	err := t.templates.ExecuteTemplate(w, name, data)
+	tpl, ok := t.templates[localeOf(c)]
+	if !ok {
+		tpl = t.templates[t.locales.Fallback()]
+	}
+	// Parsed templates are never executed, so they can be cloned with functions bound to the request.
+	tpl, err := tpl.Clone()
+	if err != nil {
+		t.logger.Error("template error", zap.Error(err))
+		return err
+	}
+	if c != nil {
+		tpl.Funcs(template.FuncMap{
+			"langURL": func(tag language.Tag) string {
+				return languageURL(c, tag)
+			},
+		})
+	}
+	err = tpl.ExecuteTemplate(w, name, data)
 	if err != nil {
 		t.logger.Error("template error", zap.Error(err))
 	}